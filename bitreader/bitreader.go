@@ -27,3 +27,24 @@ func (b *BitReader) Pop() bool {
 func (b *BitReader) HasData() bool {
 	return b.curr < len(b.bytes)
 }
+
+// Reads the next `size` bits as an unsigned integer (MSB first).
+// Mirror of BitWriter.WriteUInt
+func (b *BitReader) ReadUInt(size uint8) uint64 {
+	var val uint64
+	for range size {
+		val <<= 1
+		if b.Pop() {
+			val |= 1
+		}
+	}
+	return val
+}
+
+// Number of bits that can still be popped
+func (b *BitReader) Remaining() int {
+	if !b.HasData() {
+		return 0
+	}
+	return (len(b.bytes)-b.curr-1)*8 + b.nBits + 1
+}
@@ -64,3 +64,35 @@ func TestPopMultipleBytes(t *testing.T) {
 		}
 	}
 }
+
+func TestReadUInt(t *testing.T) {
+	r := New([]byte{0b10111100, 0xAB, 0xCD})
+
+	if got := r.ReadUInt(3); got != 0b101 {
+		t.Fatalf("expected %03b, got %03b", 0b101, got)
+	}
+	if got := r.ReadUInt(5); got != 0b11100 {
+		t.Fatalf("expected %05b, got %05b", 0b11100, got)
+	}
+	if got := r.ReadUInt(16); got != 0xABCD {
+		t.Fatalf("expected %X, got %X", 0xABCD, got)
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	r := New([]byte{0xFF, 0x00})
+
+	if r.Remaining() != 16 {
+		t.Fatalf("expected 16 bits, got %d", r.Remaining())
+	}
+
+	r.ReadUInt(5)
+	if r.Remaining() != 11 {
+		t.Fatalf("expected 11 bits, got %d", r.Remaining())
+	}
+
+	r.ReadUInt(11)
+	if r.Remaining() != 0 {
+		t.Fatalf("expected 0 bits, got %d", r.Remaining())
+	}
+}
@@ -0,0 +1,91 @@
+// Package bmp writes Windows BMP files: 1-bit for two color paletted images,
+// 8-bit for other paletted ones and 24-bit for everything else. There is no
+// alpha, transparency is flattened onto white.
+package bmp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+)
+
+const (
+	fileHeaderSize = 14
+	infoHeaderSize = 40
+)
+
+func Encode(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// Bits per pixel, and the palette for the paletted depths
+	bpp := 24
+	var palette color.Palette
+	if p, ok := img.(image.PalettedImage); ok {
+		if pal, ok := p.ColorModel().(color.Palette); ok && len(pal) <= 256 {
+			palette = pal
+			bpp = 8
+			if len(pal) <= 2 {
+				bpp = 1
+			}
+		}
+	}
+
+	// Rows are padded to 4 bytes
+	stride := (width*bpp + 31) / 32 * 4
+	offset := fileHeaderSize + infoHeaderSize + 4*len(palette)
+	fileSize := offset + stride*height
+
+	out := bufio.NewWriter(w)
+	le := binary.LittleEndian
+
+	header := make([]byte, fileHeaderSize+infoHeaderSize)
+	copy(header, "BM")
+	le.PutUint32(header[2:], uint32(fileSize))
+	le.PutUint32(header[10:], uint32(offset))
+
+	info := header[fileHeaderSize:]
+	le.PutUint32(info[0:], infoHeaderSize)
+	le.PutUint32(info[4:], uint32(width))
+	le.PutUint32(info[8:], uint32(height)) // Positive: rows go bottom up
+	le.PutUint16(info[12:], 1)             // Planes
+	le.PutUint16(info[14:], uint16(bpp))
+	le.PutUint32(info[20:], uint32(stride*height))
+	le.PutUint32(info[24:], 2835) // 72 dpi, in pixels per meter
+	le.PutUint32(info[28:], 2835)
+	le.PutUint32(info[32:], uint32(len(palette)))
+	out.Write(header)
+
+	for _, c := range palette {
+		r, g, b := flatten(c)
+		out.Write([]byte{b, g, r, 0})
+	}
+
+	row := make([]byte, stride)
+	for y := bounds.Max.Y - 1; y >= bounds.Min.Y; y-- {
+		clear(row)
+		for i := range width {
+			x := bounds.Min.X + i
+			switch bpp {
+			case 1:
+				row[i/8] |= img.(image.PalettedImage).ColorIndexAt(x, y) << (7 - i%8)
+			case 8:
+				row[i] = img.(image.PalettedImage).ColorIndexAt(x, y)
+			default:
+				r, g, b := flatten(img.At(x, y))
+				row[3*i], row[3*i+1], row[3*i+2] = b, g, r
+			}
+		}
+		out.Write(row)
+	}
+	return out.Flush()
+}
+
+// 8-bit RGB over a white background
+func flatten(c color.Color) (r, g, b uint8) {
+	cr, cg, cb, ca := c.RGBA()
+	over := func(v uint32) uint8 { return uint8((v + 0xffff - ca) >> 8) }
+	return over(cr), over(cg), over(cb)
+}
@@ -0,0 +1,59 @@
+package bmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestEncode1Bit(t *testing.T) {
+	img := image.NewPaletted(image.Rect(0, 0, 10, 3), color.Palette{color.White, color.Black})
+	img.SetColorIndex(0, 0, 1) // Top left
+	img.SetColorIndex(9, 2, 1) // Bottom right
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	le := binary.LittleEndian
+
+	// 10 pixels at 1 bit = 2 bytes, padded to 4
+	offset := 14 + 40 + 2*4
+	if len(data) != offset+3*4 || int(le.Uint32(data[2:])) != len(data) || int(le.Uint32(data[10:])) != offset {
+		t.Fatalf("file is %d bytes with header size %d, offset %d", len(data), le.Uint32(data[2:]), le.Uint32(data[10:]))
+	}
+	if bpp := le.Uint16(data[28:]); bpp != 1 {
+		t.Errorf("%d bits per pixel, expected 1", bpp)
+	}
+	if !bytes.Equal(data[54:62], []byte{255, 255, 255, 0, 0, 0, 0, 0}) {
+		t.Errorf("palette is %v", data[54:62])
+	}
+
+	// Bottom row first
+	rows := data[offset:]
+	if !bytes.Equal(rows[0:4], []byte{0, 0x40, 0, 0}) || !bytes.Equal(rows[8:12], []byte{0x80, 0, 0, 0}) {
+		t.Errorf("pixel rows are %v", rows)
+	}
+}
+
+func TestEncode24Bit(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{0x10, 0x20, 0x30, 0xff})
+	// Fully transparent: white
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	if bpp := binary.LittleEndian.Uint16(data[28:]); bpp != 24 {
+		t.Errorf("%d bits per pixel, expected 24", bpp)
+	}
+	if want := []byte{0x30, 0x20, 0x10, 0xff, 0xff, 0xff, 0, 0}; !bytes.Equal(data[54:], want) {
+		t.Errorf("pixels are %v, expected %v", data[54:], want)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"aboutblank/qr-code/qr"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+)
+
+// Exit codes of the decode subcommand
+const (
+	exitOK          = 0
+	exitError       = 1 // Bad usage, unreadable file...
+	exitNotFound    = 2 // No QR code in the image
+	exitUndecodable = 3 // QR code found, but it couldn't be decoded
+)
+
+type decodeOutput struct {
+	Text            string          `json:"text"`
+	Version         int             `json:"version"`
+	EcLevel         string          `json:"ecLevel"`
+	Mask            int             `json:"mask"`
+	Segments        []segmentOutput `json:"segments"`
+	ErrorsCorrected []int           `json:"errorsCorrected"`
+	Corners         [4]pointOutput  `json:"corners"` // top-left, top-right, bottom-right, bottom-left
+	Inverted        bool            `json:"inverted"`
+	Mirrored        bool            `json:"mirrored"`
+}
+
+type segmentOutput struct {
+	Mode      string `json:"mode"`
+	CharCount int    `json:"charCount"`
+	Data      string `json:"data"`
+	Bits      int    `json:"bits"`
+}
+
+type pointOutput struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+func runDecode(args []string) int {
+	flags := flag.NewFlagSet("decode", flag.ContinueOnError)
+	var jsonFlag = flags.Bool("json", false, "Print the full decode result as JSON")
+	// Errors and usage go to stderr, stdout is only for results (JSON with -json)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: qrgen decode [options] <image.png|jpg|gif>")
+		fmt.Fprintln(flags.Output(), "Options:")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	if flags.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "ERR: No image provided.")
+		flags.Usage()
+		return exitError
+	}
+
+	img, err := LoadImage(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERR: Failed to load image:", err)
+		return exitError
+	}
+
+	results, err := qr.DecodeImage(img)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERR:", err)
+		if errors.Is(err, qr.ErrNotFound) {
+			return exitNotFound
+		}
+		return exitUndecodable
+	}
+
+	if !*jsonFlag {
+		for _, r := range results {
+			fmt.Println(r.Text())
+		}
+		return exitOK
+	}
+
+	out := make([]decodeOutput, 0, len(results))
+	for _, r := range results {
+		out = append(out, toDecodeOutput(r))
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(out); err != nil {
+		fmt.Fprintln(os.Stderr, "ERR: Failed to write JSON:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func toDecodeOutput(r *qr.DecodeResult) decodeOutput {
+	out := decodeOutput{
+		Text:            r.Text(),
+		Version:         int(r.Version),
+		EcLevel:         getErrorCorrectionName(r.EcLevel),
+		Mask:            r.Mask,
+		Segments:        make([]segmentOutput, 0, len(r.Segments)),
+		ErrorsCorrected: r.ErrorsCorrected,
+		Inverted:        r.Inverted,
+		Mirrored:        r.Mirrored,
+	}
+
+	for _, seg := range r.Segments {
+		out.Segments = append(out.Segments, segmentOutput{
+			Mode:      seg.Mode.String(),
+			CharCount: seg.CharCount,
+			Data:      string(seg.Data),
+			Bits:      seg.Bits,
+		})
+	}
+
+	for i, c := range r.Corners {
+		out.Corners[i] = pointOutput{X: c.X, Y: c.Y}
+	}
+	return out
+}
+
+func LoadImage(fileName string) (image.Image, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	return img, err
+}
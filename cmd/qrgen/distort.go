@@ -0,0 +1,177 @@
+package main
+
+import (
+	"aboutblank/qr-code/distort"
+	"aboutblank/qr-code/qr"
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"math"
+)
+
+func runDistort(args []string) int {
+	flags := flag.NewFlagSet("distort", flag.ContinueOnError)
+	var outputFlag = flags.String("output", "distorted.png", "Output file name for the distorted image")
+	var inputFlag = flags.String("input", "", "Distort this image instead of generating a QR code from <content>")
+	var scaleFlag = flags.Int("scale", 10, "Scale factor for the generated QR code image. With -input: pixels per module of the image (measured if omitted)")
+	var errorCorrectionFlag = flags.String("ec", "M", "Error correction level (L, M, Q, H)")
+	var seedFlag = flags.Int64("seed", 1, "Random seed, the same seed always produces the same distortion")
+	var levelFlag = flags.Float64("level", 0.3, "Overall distortion level from 0 (none) to 1 (harsh), individual options override it")
+
+	var perspectiveFlag = flags.Float64("perspective", 0, "Max corner displacement, fraction of the image size")
+	var rotateFlag = flags.Float64("rotate", 0, "Max rotation in degrees")
+	var blurFlag = flags.Float64("blur", 0, "Gaussian blur sigma in pixels")
+	var noiseFlag = flags.Float64("noise", 0, "Sensor noise standard deviation (0-255)")
+	var jpegFlag = flags.Int("jpeg", 0, "JPEG recompression quality (1-100)")
+	var lightFlag = flags.Float64("light", 0, "Uneven lighting strength (0-1)")
+	var downscaleFlag = flags.Float64("downscale", 0, "Downscale factor (2 = half size)")
+
+	var batchFlag = flags.Bool("batch", false, "Report the decode rate per distortion level instead of writing an image")
+	var trialsFlag = flags.Int("trials", 20, "Batch mode: distorted copies per level")
+	var stepFlag = flags.Float64("step", 0.1, "Batch mode: step between distortion levels")
+	flags.Usage = func() {
+		fmt.Println("Usage: qrgen distort [options] <content>")
+		fmt.Println("       qrgen distort [options] -input <image>")
+		fmt.Println("       qrgen distort -batch [options] <content>")
+		fmt.Println("       qrgen distort -batch [options] -input <image>")
+		fmt.Println("Options:")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	if *errorCorrectionFlag != "L" && *errorCorrectionFlag != "M" && *errorCorrectionFlag != "Q" && *errorCorrectionFlag != "H" {
+		fmt.Println("ERR: Invalid error correction level. Must be one of L, M, Q, H.")
+		return exitError
+	}
+
+	var img image.Image
+	var expected []byte
+	scale := *scaleFlag
+	if *inputFlag != "" {
+		var err error
+		img, err = LoadImage(*inputFlag)
+		if err != nil {
+			fmt.Println("ERR: Failed to load image:", err)
+			return exitError
+		}
+
+		results, decodeErr := qr.DecodeImage(img)
+		scaleSet := false
+		flags.Visit(func(f *flag.Flag) { scaleSet = scaleSet || f.Name == "scale" })
+		if !scaleSet {
+			// The presets are relative to the module size, which the image has to tell
+			if decodeErr != nil {
+				fmt.Println("ERR: Can't measure the module size of the -input image, set it with -scale")
+				return exitError
+			}
+			scale = moduleSize(results[0])
+		}
+
+		if *batchFlag {
+			// The batch checks the distorted copies against what the original holds
+			if decodeErr != nil || len(results) != 1 {
+				fmt.Println("ERR: -batch needs an -input image with exactly one readable QR code")
+				return exitError
+			}
+			expected = results[0].Data
+		}
+	} else {
+		if flags.NArg() < 1 {
+			fmt.Println("ERR: No content provided.")
+			flags.Usage()
+			return exitError
+		}
+
+		content := flags.Arg(0)
+		expected = []byte(content)
+		qrCode, err := qr.GenerateQRCodeWithOptions(content, qr.EncodeOptions{EcLevel: getErrorCorrectionLevel(*errorCorrectionFlag)})
+		if err != nil {
+			fmt.Println("ERR:", err)
+			return exitError
+		}
+		img = qrCode.GenerateImage(*scaleFlag)
+	}
+
+	// Explicitly set options override the preset
+	options := func(level float64, seed int64) distort.Options {
+		opts := distort.Preset(level, scale, seed)
+		flags.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "perspective":
+				opts.Perspective = *perspectiveFlag
+			case "rotate":
+				opts.Rotation = *rotateFlag
+			case "blur":
+				opts.Blur = *blurFlag
+			case "noise":
+				opts.Noise = *noiseFlag
+			case "jpeg":
+				opts.JPEGQuality = *jpegFlag
+			case "light":
+				opts.Lighting = *lightFlag
+			case "downscale":
+				opts.Downscale = *downscaleFlag
+			}
+		})
+		return opts
+	}
+
+	if *batchFlag {
+		if *stepFlag <= 0 {
+			fmt.Println("ERR: -step must be positive.")
+			return exitError
+		}
+		if *trialsFlag <= 0 {
+			fmt.Println("ERR: -trials must be positive.")
+			return exitError
+		}
+		return runDistortBatch(img, expected, options, *trialsFlag, *stepFlag, *seedFlag)
+	}
+
+	distorted, err := distort.Apply(img, options(*levelFlag, *seedFlag))
+	if err != nil {
+		fmt.Println("ERR: Failed to distort image:", err)
+		return exitError
+	}
+
+	if err := SaveImage(distorted, *outputFlag, ""); err != nil {
+		fmt.Println("ERR: Failed to save image:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// Pixels per module of a decoded symbol, along its top edge
+func moduleSize(r *qr.DecodeResult) int {
+	modules := float64(17 + 4*int(r.Version))
+	top := math.Hypot(r.Corners[1].X-r.Corners[0].X, r.Corners[1].Y-r.Corners[0].Y)
+	return max(int(math.Round(top/modules)), 1)
+}
+
+func runDistortBatch(img image.Image, expected []byte, options func(float64, int64) distort.Options, trials int, step float64, seed int64) int {
+	fmt.Printf("%d trials per level, seed %d\n\n", trials, seed)
+	fmt.Printf("%6s  %7s\n", "level", "decoded")
+
+	for level := 0.0; level <= 1+1e-9; level += step {
+		decoded := 0
+		for i := range trials {
+			distorted, err := distort.Apply(img, options(level, seed+int64(i)))
+			if err != nil {
+				fmt.Println("ERR: Failed to distort image:", err)
+				return exitError
+			}
+
+			results, err := qr.DecodeImage(distorted)
+			if err == nil && len(results) == 1 && bytes.Equal(results[0].Data, expected) {
+				decoded++
+			}
+		}
+		fmt.Printf("%6.2f  %6.0f%%\n", level, float64(decoded)*100/float64(trials))
+	}
+
+	return exitOK
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"aboutblank/qr-code/qr"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+func runGrade(args []string) int {
+	flags := flag.NewFlagSet("grade", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Println("Usage: qrgen grade <image.png|jpg|gif>")
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	if flags.NArg() < 1 {
+		fmt.Println("ERR: No image provided.")
+		flags.Usage()
+		return exitError
+	}
+
+	img, err := LoadImage(flags.Arg(0))
+	if err != nil {
+		fmt.Println("ERR: Failed to load image:", err)
+		return exitError
+	}
+
+	reports, err := qr.GradeImage(img)
+	if err != nil {
+		fmt.Println("ERR:", err)
+		if errors.Is(err, qr.ErrNotFound) {
+			return exitNotFound
+		}
+		return exitUndecodable
+	}
+
+	for i, report := range reports {
+		if i > 0 {
+			fmt.Println()
+		}
+		printReport(report)
+	}
+	return exitOK
+}
+
+func printReport(r *qr.QualityReport) {
+	fmt.Printf("Content: %q (version %d-%s)\n\n", r.Text(), r.Version, getErrorCorrectionName(r.EcLevel))
+
+	fmt.Printf("  %-24s %-8s %s\n", "Decode", "", r.Decode)
+	fmt.Printf("  %-24s %-8s %s\n", "Symbol contrast", fmt.Sprintf("%.1f%%", r.SymbolContrast.Value), r.SymbolContrast.Grade)
+	fmt.Printf("  %-24s %-8.2f %s\n", "Modulation", r.Modulation.Value, r.Modulation.Grade)
+	fmt.Printf("  %-24s %-8.2f %s\n", "Reflectance margin", r.ReflectanceMargin.Value, r.ReflectanceMargin.Grade)
+	fmt.Printf("  %-24s %-8s %s\n", "Fixed pattern damage", "", r.FixedPatternDamage)
+	for _, p := range r.Patterns {
+		fmt.Printf("    %-22s %-8s %s\n", p.Name, fmt.Sprintf("%d/%d", p.Damaged, p.Modules), p.Grade)
+	}
+	fmt.Printf("  %-24s %-8.3f %s\n", "Axial non-uniformity", r.AxialNonUniformity.Value, r.AxialNonUniformity.Grade)
+	fmt.Printf("  %-24s %-8.2f %s\n", "Grid non-uniformity", r.GridNonUniformity.Value, r.GridNonUniformity.Grade)
+	fmt.Printf("  %-24s %-8s %s\n", "Unused EC", fmt.Sprintf("%.0f%%", r.UnusedEC.Value*100), r.UnusedEC.Grade)
+
+	fmt.Printf("\nOverall grade: %s (Rmax %.1f%%, Rmin %.1f%%)\n", r.Overall, r.Rmax, r.Rmin)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"aboutblank/qr-code/qr"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+func runInspect(args []string) int {
+	flags := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Println("Usage: qrgen inspect <image.png|jpg|gif>")
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	if flags.NArg() < 1 {
+		fmt.Println("ERR: No image provided.")
+		flags.Usage()
+		return exitError
+	}
+
+	img, err := LoadImage(flags.Arg(0))
+	if err != nil {
+		fmt.Println("ERR: Failed to load image:", err)
+		return exitError
+	}
+
+	inspections, err := qr.InspectImage(img)
+	if err != nil {
+		fmt.Println("ERR:", err)
+		if errors.Is(err, qr.ErrNotFound) {
+			return exitNotFound
+		}
+		return exitUndecodable
+	}
+
+	for i, inspection := range inspections {
+		if i > 0 {
+			fmt.Println()
+		}
+		printInspection(inspection)
+	}
+	return exitOK
+}
+
+func printInspection(i *qr.Inspection) {
+	fmt.Printf("Content:   %q\n", i.Text())
+	fmt.Printf("Version:   %d (%dx%d)\n", i.Version, 4*int(i.Version)+17, 4*int(i.Version)+17)
+	fmt.Printf("EC level:  %s\n", getErrorCorrectionName(i.EcLevel))
+	fmt.Printf("Mask:      %d\n", i.Mask)
+	if i.Inverted || i.Mirrored {
+		fmt.Printf("Inverted:  %t, mirrored: %t\n", i.Inverted, i.Mirrored)
+	}
+
+	lowest := i.MaskScores[0]
+	for _, score := range i.MaskScores {
+		lowest = min(lowest, score)
+	}
+
+	fmt.Println("\nMask penalties:")
+	for mask, score := range i.MaskScores {
+		note := ""
+		if mask == i.Mask {
+			note += " (used)"
+		}
+		if score == lowest {
+			note += " (lowest)"
+		}
+		fmt.Printf("  %d: %6d%s\n", mask, score, note)
+	}
+
+	fmt.Println("\nSegments:")
+	for _, seg := range i.Segments {
+		fmt.Printf("  %-12s %4d chars %5d bits\n", seg.Mode, seg.CharCount, seg.Bits)
+	}
+	fmt.Printf("Data bits: %d / %d (%.1f%% used)\n", i.DataBits, i.CapacityBits, 100*float64(i.DataBits)/float64(i.CapacityBits))
+	fmt.Printf("Pad bytes: %d", i.PadBytes)
+	if i.PadBytes > 0 && !i.PadPattern {
+		fmt.Print(" (non-standard pattern)")
+	}
+	fmt.Println()
+
+	fmt.Printf("\nEC errors corrected per block (max %d):\n ", i.CorrectionCapacity())
+	for _, n := range i.ErrorsCorrected {
+		fmt.Printf(" %d", n)
+	}
+	fmt.Println()
+
+	fmt.Println()
+	switch {
+	case i.MatchesEncoder():
+		fmt.Println("Encoder:   matches this encoder's output")
+	case i.ModulesDiffer < 0:
+		fmt.Printf("Encoder:   differs, this encoder would use version %d, mask %d (%s mode)\n", i.EncoderVersion, i.EncoderMask, i.EncoderMode)
+	default:
+		fmt.Printf("Encoder:   differs in %d modules, this encoder would use version %d, mask %d (%s mode)\n", i.ModulesDiffer, i.EncoderVersion, i.EncoderMask, i.EncoderMode)
+	}
+}
@@ -1,21 +1,61 @@
 package main
 
 import (
+	"aboutblank/qr-code/pdf"
 	"aboutblank/qr-code/qr"
+	"aboutblank/qr-code/sixel"
 	"flag"
 	"fmt"
 	"image"
-	"image/png"
 	"os"
 )
 
 func main() {
+	// Subcommands have their own flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "decode":
+			os.Exit(runDecode(os.Args[2:]))
+		case "grade":
+			os.Exit(runGrade(os.Args[2:]))
+		case "inspect":
+			os.Exit(runInspect(os.Args[2:]))
+		case "stress":
+			os.Exit(runStress(os.Args[2:]))
+		case "distort":
+			os.Exit(runDistort(os.Args[2:]))
+		}
+	}
+
 	var helpFlag = flag.Bool("help", false, "Display help information")
 	var scaleFlag = flag.Int("scale", 10, "Scale factor for the generated QR code image")
 	var outputFlag = flag.String("output", "qrcode.png", "Output file name for the generated QR code image")
+	var formatFlag = flag.String("format", "", "Output format: png, svg, pdf, eps, gif, jpeg, bmp, pbm, pgm, or term/sixel/auto to print to the terminal. Taken from the -output extension if omitted")
 	var versionOverrideFlag = flag.Int("version", 0, "Override QR code version (1-40). If ommitted, the version will be automatically determined based on the content length.")
 	var errorCorrectionFlag = flag.String("ec", "M", "Error correction level (L, M, Q, H)")
 	var verboseFlag = flag.Bool("verbose", false, "Enable verbose output")
+	var invertFlag = flag.Bool("invert", false, "Generate light modules on a dark background")
+	var verifyFlag = flag.Bool("verify", false, "Decode the generated QR code and fail unless it reads back exactly as the content")
+	var fgFlag = flag.String("fg", "", "Images/SVG: color of the dark modules as hex, e.g. #1a237e (default: black)")
+	var bgFlag = flag.String("bg", "", "Images/SVG: background color as hex, or \"transparent\" (default: white)")
+	var fillFlag = flag.String("fill", "", "Images/SVG: fill the dark modules with linear:<hex>,<hex>,..., radial:<hex>,<hex>,... or image:<file>")
+	var allowLowContrastFlag = flag.Bool("allow-low-contrast", false, "Images/SVG: only warn about inverted or low contrast -fg/-bg colors instead of refusing them")
+	var moduleStyleFlag = flag.String("module-style", "square", "Images/SVG: module shape: square, circle, rounded or connected")
+	var finderStyleFlag = flag.String("finder-style", "square", "Images/SVG: finder pattern shape: square, rounded or circle")
+	var logoFlag = flag.String("logo", "", "Images/SVG: picture to put in the middle of the QR code. The EC level/version are raised until it's safe")
+	var logoSizeFlag = flag.Float64("logo-size", 0.2, "Largest side of the logo, as a fraction of the QR code width")
+	var logoPaddingFlag = flag.Int("logo-padding", 1, "Light modules around the logo")
+	var moduleMMFlag = flag.Float64("module-mm", 0.5, "PDF/EPS: size of a module in millimeters")
+	var bleedMMFlag = flag.Float64("bleed-mm", 0, "PDF: bleed around the trim box in millimeters")
+	var cropMarksFlag = flag.Bool("crop-marks", false, "PDF: add crop marks")
+	var cmykFlag = flag.String("cmyk", "", "EPS: ink color as C,M,Y,K percentages, e.g. 100,80,0,10 (default: black)")
+	var asciiFlag = flag.Bool("ascii", false, "term: plain ASCII instead of Unicode blocks and colors")
+	var compactFlag = flag.Bool("compact", false, "Leave out the quiet zone, same as -quiet-zone 0")
+	var quietZoneFlag = flag.Int("quiet-zone", 4, "Light modules around the QR code, 0 for none")
+	var sizeFlag = flag.Int("size", 0, "Images/sixel: target width/height in pixels, replaces -scale")
+	var centerFlag = flag.Bool("center", false, "Images/sixel with -size: exactly -size pixels, the code centered")
+	var smoothFlag = flag.Bool("smooth", false, "Images with -size: exactly -size pixels, fractional scale with anti-aliasing")
+	var spotFlag = flag.String("spot", "", "EPS: print with this spot color (e.g. \"PANTONE 286 C\"), -cmyk is its fallback")
 
 	flag.Parse()
 
@@ -29,28 +69,247 @@ func main() {
 	if flag.NArg() < 1 {
 		fmt.Println("ERR: No content provided.")
 		fmt.Println("Usage: qrgen [options] <content>")
-		return
+		os.Exit(1)
 	}
 
 	if *versionOverrideFlag < 0 || *versionOverrideFlag > 40 {
 		fmt.Println("ERR: Version must be between 1 and 40.")
-		return
+		os.Exit(1)
 	}
 
 	if *errorCorrectionFlag != "L" && *errorCorrectionFlag != "M" && *errorCorrectionFlag != "Q" && *errorCorrectionFlag != "H" {
 		fmt.Println("ERR: Invalid error correction level. Must be one of L, M, Q, H.")
-		return
+		os.Exit(1)
+	}
+
+	format, err := outputFormat(*formatFlag, *outputFlag)
+	if err != nil {
+		fmt.Println("ERR:", err)
+		os.Exit(1)
+	}
+	if format == "auto" {
+		format = terminalFormat()
+	}
+	if err := checkFormatFlags(format); err != nil {
+		fmt.Println("ERR:", err)
+		os.Exit(1)
+	}
+
+	if *scaleFlag <= 0 && *sizeFlag <= 0 {
+		fmt.Println("ERR: Scale must be positive.")
+		os.Exit(1)
+	}
+
+	if *quietZoneFlag < 0 {
+		fmt.Println("ERR: Quiet zone can't be negative.")
+		os.Exit(1)
+	}
+	quietZone := *quietZoneFlag
+	if *compactFlag {
+		quietZone = 0
+	}
+
+	fg, bg, err := parseColors(*fgFlag, *bgFlag)
+	if err != nil {
+		fmt.Println("ERR:", err)
+		os.Exit(1)
+	}
+	if *fgFlag != "" || *bgFlag != "" {
+		if err := qr.CheckColors(fg, bg); err != nil {
+			if !*allowLowContrastFlag {
+				fmt.Println("ERR:", err, "(-allow-low-contrast to use them anyway)")
+				os.Exit(1)
+			}
+			fmt.Println("WARN:", err)
+		}
+	}
+
+	var fill qr.Fill
+	if *fillFlag != "" {
+		fill, err = parseFill(*fillFlag)
+		if err != nil {
+			fmt.Println("ERR:", err)
+			os.Exit(1)
+		}
+		if err := qr.CheckFill(fill, bg); err != nil {
+			fmt.Println("WARN:", err)
+		}
+	}
+
+	// Inverting swaps the colors, the modules would be the see-through part
+	if _, _, _, a := bg.RGBA(); *invertFlag && fill == nil && a != 0xffff {
+		fmt.Println("ERR: -invert doesn't work with a transparent -bg, the modules would be transparent")
+		os.Exit(1)
+	}
+
+	moduleStyle, err := qr.ParseModuleStyle(*moduleStyleFlag)
+	if err != nil {
+		fmt.Println("ERR:", err)
+		os.Exit(1)
+	}
+	finderStyle, err := qr.ParseFinderStyle(*finderStyleFlag)
+	if err != nil {
+		fmt.Println("ERR:", err)
+		os.Exit(1)
 	}
 
 	content := flag.Arg(0)
-	qrCode := qr.GenerateQRCode(content, getErrorCorrectionLevel(*errorCorrectionFlag), *versionOverrideFlag, *verboseFlag)
-	image := qrCode.GenerateImage(*scaleFlag)
-	err := SaveImage(image, *outputFlag)
+	opts := qr.EncodeOptions{
+		EcLevel: getErrorCorrectionLevel(*errorCorrectionFlag),
+		Version: *versionOverrideFlag,
+		Verbose: *verboseFlag,
+		Verify:  *verifyFlag,
+	}
+
+	if *logoFlag != "" {
+		logo, err := LoadImage(*logoFlag)
+		if err != nil {
+			fmt.Println("ERR: Failed to load logo:", err)
+			os.Exit(1)
+		}
+		opts.Logo = &qr.Logo{Image: logo, MaxSize: *logoSizeFlag, Padding: *logoPaddingFlag}
+	}
 
+	qrCode, err := qr.GenerateQRCodeWithOptions(content, opts)
 	if err != nil {
-		fmt.Println("ERR: Failed to save image:", err)
+		fmt.Println("ERR:", err)
+		os.Exit(1)
+	}
+
+	if format == "pdf" {
+		// Every content argument gets its own page
+		codes := []*qr.QRCode{qrCode}
+		for _, page := range flag.Args()[1:] {
+			pageCode, err := qr.GenerateQRCodeWithOptions(page, opts)
+			if err != nil {
+				fmt.Println("ERR:", err)
+				os.Exit(1)
+			}
+			codes = append(codes, pageCode)
+		}
+
+		err := SavePDF(codes, *outputFlag, qr.PDFOptions{
+			ModuleSize: pdf.MM(*moduleMMFlag),
+			QuietZone:  qr.QuietZone(quietZone),
+			Bleed:      pdf.MM(*bleedMMFlag),
+			CropMarks:  *cropMarksFlag,
+			Invert:     *invertFlag,
+		})
+		if err != nil {
+			fmt.Println("ERR: Failed to save PDF:", err)
+			os.Exit(1)
+		}
 		return
 	}
+
+	if format == "sixel" {
+		img := qrCode.PalettedImage(qr.ImageOptions{
+			Scale:     *scaleFlag,
+			QuietZone: qr.QuietZone(quietZone),
+			Invert:    *invertFlag,
+			Size:      *sizeFlag,
+			Center:    *centerFlag,
+		})
+		if err := sixel.Encode(os.Stdout, img); err != nil {
+			fmt.Println("ERR:", err)
+			os.Exit(1)
+		}
+		fmt.Println()
+		return
+	}
+
+	if format == "term" {
+		opts := qr.TerminalOptions{ASCII: *asciiFlag, Invert: *invertFlag, QuietZone: qr.QuietZone(quietZone)}
+		if err := qrCode.WriteTerminal(os.Stdout, opts); err != nil {
+			fmt.Println("ERR:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if format == "eps" {
+		ink, err := parseInk(*cmykFlag, *spotFlag)
+		if err != nil {
+			fmt.Println("ERR:", err)
+			os.Exit(1)
+		}
+
+		err = SaveEPS(qrCode, *outputFlag, qr.EPSOptions{
+			ModuleSize: pdf.MM(*moduleMMFlag),
+			QuietZone:  qr.QuietZone(quietZone),
+			Ink:        ink,
+			Title:      content,
+		})
+		if err != nil {
+			fmt.Println("ERR: Failed to save EPS:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if format == "svg" {
+		svgOpts := qr.SVGOptions{
+			Scale:       *scaleFlag,
+			QuietZone:   qr.QuietZone(quietZone),
+			Foreground:  svgColor(fg),
+			Background:  svgColor(bg),
+			ModuleStyle: moduleStyle,
+			FinderStyle: finderStyle,
+			Fill:        fill,
+			Invert:      *invertFlag,
+		}
+		if err := SaveSVG(qrCode, *outputFlag, svgOpts); err != nil {
+			fmt.Println("ERR: Failed to save SVG:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	imageOpts := qr.ImageOptions{
+		Scale:      *scaleFlag,
+		QuietZone:  qr.QuietZone(quietZone),
+		Invert:     *invertFlag,
+		Foreground: fg,
+		Background: bg,
+		Size:       *sizeFlag,
+		Center:     *centerFlag,
+		Smooth:     *smoothFlag,
+
+		ModuleStyle: moduleStyle,
+		FinderStyle: finderStyle,
+		Fill:        fill,
+	}
+
+	// The pixels that get written: paletted (1 byte per pixel) when it's two
+	// colors, that's also what the streamed PNG holds. Only built when needed.
+	var img image.Image
+	if *verifyFlag || format != "png" {
+		if qrCode.TwoColor(imageOpts) {
+			img = qrCode.PalettedImage(imageOpts)
+		} else {
+			img = qrCode.GenerateImageWithOptions(imageOpts)
+		}
+	}
+
+	// The image that actually gets written has to read back too
+	if *verifyFlag {
+		if err := qr.VerifyImage(img, []byte(content)); err != nil {
+			fmt.Println("ERR:", err)
+			os.Exit(1)
+		}
+	}
+
+	if format == "png" {
+		// 1-bit when it's just two colors, streamed row by row
+		err = SavePNG(qrCode, *outputFlag, imageOpts)
+	} else {
+		err = SaveImage(img, *outputFlag, format)
+	}
+
+	if err != nil {
+		fmt.Println("ERR: Failed to save image:", err)
+		os.Exit(1)
+	}
 }
 
 func getErrorCorrectionLevel(ec string) qr.ErrorCorrectionLevel {
@@ -68,22 +327,42 @@ func getErrorCorrectionLevel(ec string) qr.ErrorCorrectionLevel {
 	}
 }	
 
+func getErrorCorrectionName(ecLevel qr.ErrorCorrectionLevel) string {
+	switch ecLevel {
+	case qr.EC_Low:
+		return "L"
+	case qr.EC_Medium:
+		return "M"
+	case qr.EC_Quartile:
+		return "Q"
+	case qr.EC_High:
+		return "H"
+	}
+	return "?"
+}
+
 func PrintHelp() {
 	fmt.Println("Usage: qrgen [options] <content>")
+	fmt.Println("       qrgen decode [-json] <image>")
+	fmt.Println("       qrgen inspect <image>")
+	fmt.Println("       qrgen grade <image>")
+	fmt.Println("       qrgen stress [options] <content>")
+	fmt.Println("       qrgen distort [options] <content>")
 	fmt.Println("Options:")
 	flag.PrintDefaults()
 }
 
-func SaveImage(image *image.RGBA, fileName string) error {
+// The format is taken from the file extension if empty (png if unknown)
+func SaveImage(image image.Image, fileName string, format string) error {
+	if format == "" {
+		format, _ = outputFormat("", fileName)
+	}
+
 	f, err := os.Create(fileName)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	err = png.Encode(f, image)
-	if err != nil {
-		return err
-	}
-	return nil
+	return encodeImage(f, image, format)
 }
\ No newline at end of file
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"aboutblank/qr-code/bmp"
+	"aboutblank/qr-code/netpbm"
+	"aboutblank/qr-code/qr"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Output formats qrgen can write. File formats can also be picked by the
+// -output extension, the others print to stdout.
+var (
+	fileFormats   = []string{"png", "svg", "pdf", "eps", "gif", "jpeg", "bmp", "pbm", "pgm"}
+	outputFormats = append(fileFormats, "term", "sixel", "auto")
+)
+
+// Formats rendered as pixels, everything the image renderer can do works there
+var (
+	rasterFormats = []string{"png", "gif", "jpeg", "bmp", "pbm", "pgm"}
+	styledFormats = append(slices.Clone(rasterFormats), "svg")
+)
+
+// The formats that use a flag, flags that aren't listed work with all of them.
+// Anything else would be silently dropped, so it's an error.
+var flagFormats = map[string][]string{
+	"logo":               styledFormats,
+	"logo-size":          styledFormats,
+	"logo-padding":       styledFormats,
+	"fg":                 styledFormats,
+	"bg":                 styledFormats,
+	"allow-low-contrast": styledFormats,
+	"fill":               styledFormats,
+	"module-style":       styledFormats,
+	"finder-style":       styledFormats,
+	"invert":             append(slices.Clone(styledFormats), "pdf", "term", "sixel"),
+	"scale":              append(slices.Clone(styledFormats), "sixel"),
+	"size":               append(slices.Clone(rasterFormats), "sixel"),
+	"center":             append(slices.Clone(rasterFormats), "sixel"),
+	"smooth":             rasterFormats,
+	"module-mm":          {"pdf", "eps"},
+	"bleed-mm":           {"pdf"},
+	"crop-marks":         {"pdf"},
+	"cmyk":               {"eps"},
+	"spot":               {"eps"},
+	"ascii":              {"term"},
+}
+
+// Error for the first flag that was set but does nothing in format
+func checkFormatFlags(format string) error {
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		if formats, ok := flagFormats[f.Name]; ok && err == nil && !slices.Contains(formats, format) {
+			err = fmt.Errorf("-%s doesn't work with -format %s, only with %s", f.Name, format, strings.Join(formats, ", "))
+		}
+	})
+	return err
+}
+
+// Terminals known to show Sixel graphics, by $TERM
+var sixelTerms = []string{"mlterm", "foot", "foot-extra", "wezterm", "yaft-256color", "contour"}
+
+// The -format flag wins, otherwise the -output extension decides (png if unknown)
+func outputFormat(format, fileName string) (string, error) {
+	if format == "" {
+		format = jpegAlias(strings.TrimPrefix(strings.ToLower(filepath.Ext(fileName)), "."))
+		if !slices.Contains(fileFormats, format) {
+			return "png", nil
+		}
+		return format, nil
+	}
+
+	format = jpegAlias(strings.ToLower(format))
+	if !slices.Contains(outputFormats, format) {
+		return "", fmt.Errorf("unknown format %q, must be one of %s", format, strings.Join(outputFormats, ", "))
+	}
+	return format, nil
+}
+
+func jpegAlias(format string) string {
+	if format == "jpg" {
+		return "jpeg"
+	}
+	return format
+}
+
+// "auto" prints Sixel graphics where the terminal can show them, text otherwise
+func terminalFormat() string {
+	// Every terminal claims to be some xterm, only the real one sets XTERM_VERSION
+	if slices.Contains(sixelTerms, os.Getenv("TERM")) || os.Getenv("TERM_PROGRAM") == "WezTerm" || os.Getenv("XTERM_VERSION") != "" {
+		return "sixel"
+	}
+	return "term"
+}
+
+func SavePNG(qrCode *qr.QRCode, fileName string, opts qr.ImageOptions) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return qrCode.WritePNG(f, opts)
+}
+
+// Raster formats other than png go through here, png has the streaming SavePNG
+func encodeImage(w io.Writer, img image.Image, format string) error {
+	switch format {
+	case "png":
+		return png.Encode(w, img)
+	case "gif":
+		return gif.Encode(w, img, nil)
+	case "jpeg":
+		// High quality, module edges smear at the default
+		return jpeg.Encode(w, flattenOnWhite(img), &jpeg.Options{Quality: 95})
+	case "bmp":
+		return bmp.Encode(w, img)
+	case "pbm":
+		return netpbm.EncodePBM(w, img)
+	case "pgm":
+		return netpbm.EncodePGM(w, img)
+	}
+	return fmt.Errorf("%s is not a raster image format", format)
+}
+
+// JPEG has no alpha, transparency goes onto white like the bmp and netpbm
+// encoders do instead of turning black
+func flattenOnWhite(img image.Image) image.Image {
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return img
+	}
+
+	// Two color codes only need their palette flattened
+	if p, ok := img.(*image.Paletted); ok {
+		flat := *p
+		flat.Palette = make(color.Palette, len(p.Palette))
+		for i, c := range p.Palette {
+			r, g, b, a := c.RGBA()
+			over := func(v uint32) uint8 { return uint8((v + 0xffff - a) >> 8) }
+			flat.Palette[i] = color.RGBA{over(r), over(g), over(b), 0xff}
+		}
+		return &flat
+	}
+
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+	return flat
+}
+
+func SaveSVG(qrCode *qr.QRCode, fileName string, opts qr.SVGOptions) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return qrCode.WriteSVG(f, opts)
+}
+
+func SavePDF(codes []*qr.QRCode, fileName string, opts qr.PDFOptions) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return qr.WritePDF(f, codes, opts)
+}
+
+func SaveEPS(qrCode *qr.QRCode, fileName string, opts qr.EPSOptions) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return qrCode.WriteEPS(f, opts)
+}
+
+// cmyk is "C,M,Y,K" in percent, empty = black
+func parseInk(cmyk, spot string) (qr.Ink, error) {
+	ink := qr.Ink{K: 1, Spot: spot}
+	if cmyk == "" {
+		return ink, nil
+	}
+
+	parts := strings.Split(cmyk, ",")
+	if len(parts) != 4 {
+		return ink, fmt.Errorf("invalid CMYK color %q, expected 4 comma separated percentages", cmyk)
+	}
+	var values [4]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || v < 0 || v > 100 {
+			return ink, fmt.Errorf("invalid CMYK component %q, must be between 0 and 100", part)
+		}
+		values[i] = v / 100
+	}
+	ink.C, ink.M, ink.Y, ink.K = values[0], values[1], values[2], values[3]
+	return ink, nil
+}
+
+// -fg and -bg, black on white when empty
+func parseColors(fg, bg string) (color.NRGBA, color.NRGBA, error) {
+	fgColor, err := parseHexColor(fg, color.NRGBA{0, 0, 0, 255})
+	if err != nil {
+		return fgColor, fgColor, err
+	}
+	bgColor, err := parseHexColor(bg, color.NRGBA{255, 255, 255, 255})
+	return fgColor, bgColor, err
+}
+
+// #rgb, #rrggbb or #rrggbbaa (the # is optional), or "transparent"
+func parseHexColor(s string, fallback color.NRGBA) (color.NRGBA, error) {
+	if s == "" {
+		return fallback, nil
+	}
+	if strings.EqualFold(s, "transparent") {
+		return color.NRGBA{}, nil
+	}
+
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 8 {
+		return fallback, fmt.Errorf("invalid color %q, expected #rgb, #rrggbb or #rrggbbaa", s)
+	}
+	return color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
+
+// CSS for a parsed color
+func svgColor(c color.NRGBA) string {
+	switch c.A {
+	case 0:
+		return "none"
+	case 255:
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+}
+
+// -fill: linear:<color>,<color>,... (top left to bottom right),
+// radial:<color>,<color>,... (center outwards) or image:<file>
+func parseFill(spec string) (qr.Fill, error) {
+	kind, arg, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid fill %q, expected linear:<colors>, radial:<colors> or image:<file>", spec)
+	}
+
+	if kind == "image" {
+		img, err := LoadImage(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load fill image: %w", err)
+		}
+		return qr.ImageFill{Image: img}, nil
+	}
+
+	colors := strings.Split(arg, ",")
+	if len(colors) < 2 {
+		return nil, fmt.Errorf("a gradient needs at least 2 colors, got %q", arg)
+	}
+	stops := make([]qr.GradientStop, len(colors))
+	for i, hex := range colors {
+		c, err := parseHexColor(strings.TrimSpace(hex), color.NRGBA{})
+		if err != nil {
+			return nil, err
+		}
+		stops[i] = qr.GradientStop{Offset: float64(i) / float64(len(colors)-1), Color: c}
+	}
+
+	switch kind {
+	case "linear":
+		return qr.LinearGradient{X1: 1, Y1: 1, Stops: stops}, nil
+	case "radial":
+		// Out to the corners
+		return qr.RadialGradient{CX: 0.5, CY: 0.5, R: math.Sqrt2 / 2, Stops: stops}, nil
+	}
+	return nil, fmt.Errorf("unknown fill %q, must be linear, radial or image", kind)
+}
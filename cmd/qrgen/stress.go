@@ -0,0 +1,92 @@
+package main
+
+import (
+	"aboutblank/qr-code/qr"
+	"flag"
+	"fmt"
+)
+
+func runStress(args []string) int {
+	flags := flag.NewFlagSet("stress", flag.ContinueOnError)
+	var damageFlag = flags.String("damage", "flip", "Kind of damage: flip, blank or scratch")
+	var trialsFlag = flags.Int("trials", 20, "Damaged copies per damage level")
+	var seedFlag = flags.Int64("seed", 1, "Random seed, the same seed always produces the same damage")
+	var maxFlag = flags.Float64("max", 30, "Highest damage level to test (% of modules)")
+	var stepFlag = flags.Float64("step", 2, "Step between damage levels (% of modules)")
+	var versionOverrideFlag = flags.Int("version", 0, "Override QR code version (1-40) for every EC level")
+	var renderFlag = flags.Bool("render", false, "Decode the rendered image instead of the module matrix (slower, finder damage counts)")
+	flags.Usage = func() {
+		fmt.Println("Usage: qrgen stress [options] <content>")
+		fmt.Println("Options:")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	if flags.NArg() < 1 {
+		fmt.Println("ERR: No content provided.")
+		flags.Usage()
+		return exitError
+	}
+
+	var kind qr.DamageKind
+	switch *damageFlag {
+	case "flip":
+		kind = qr.Damage_Flip
+	case "blank":
+		kind = qr.Damage_Blank
+	case "scratch":
+		kind = qr.Damage_Scratch
+	default:
+		fmt.Println("ERR: Invalid damage kind. Must be one of flip, blank, scratch.")
+		return exitError
+	}
+
+	if *trialsFlag <= 0 {
+		fmt.Println("ERR: -trials must be positive.")
+		return exitError
+	}
+
+	if *stepFlag <= 0 || *maxFlag < 0 {
+		fmt.Println("ERR: -step must be positive and -max can't be negative.")
+		return exitError
+	}
+
+	var levels []float64
+	for pct := 0.0; pct <= *maxFlag+1e-9; pct += *stepFlag {
+		levels = append(levels, pct)
+	}
+
+	results, err := qr.StressTest(flags.Arg(0), qr.StressOptions{
+		Kind:    kind,
+		Levels:  levels,
+		Trials:  *trialsFlag,
+		Seed:    *seedFlag,
+		Version: *versionOverrideFlag,
+		Render:  *renderFlag,
+	})
+	if err != nil {
+		fmt.Println("ERR:", err)
+		return exitError
+	}
+
+	fmt.Printf("Damage: %s, %d trials per level, seed %d\n\n", kind, *trialsFlag, *seedFlag)
+
+	fmt.Printf("%8s", "damage")
+	for _, r := range results {
+		fmt.Printf("  %9s", fmt.Sprintf("%s (v%d)", getErrorCorrectionName(r.EcLevel), r.Version))
+	}
+	fmt.Println()
+
+	for i, level := range levels {
+		fmt.Printf("%7.1f%%", level)
+		for _, r := range results {
+			fmt.Printf("  %8.0f%%", r.Points[i].SuccessRate()*100)
+		}
+		fmt.Println()
+	}
+
+	return exitOK
+}
@@ -0,0 +1,339 @@
+// Package distort makes synthetic "photos" of an image: perspective, rotation,
+// blur, noise, JPEG artifacts, uneven lighting and downscaling.
+// All randomness comes from the seed, the same options always give the same result.
+package distort
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"math"
+	"math/rand"
+)
+
+type Options struct {
+	Perspective float64 // Max corner displacement, as a fraction of the image size (0-0.5)
+	Rotation    float64 // Max rotation in degrees, the actual angle is random in [-Rotation, Rotation]
+	Blur        float64 // Gaussian blur sigma, in pixels
+	Noise       float64 // Standard deviation of the sensor noise (0-255 scale)
+	JPEGQuality int     // Recompress as JPEG with this quality (1-100), 0 = off
+	Lighting    float64 // Strength of the uneven lighting (0-1), 1 = the dark side is black
+	Downscale   float64 // Shrink the image by this factor, values <= 1 are ignored
+	Seed        int64
+}
+
+// Preset scales every distortion with a single level between 0 (none) and 1 (harsh).
+// scale is the amount of pixels per module, blur is relative to it.
+func Preset(level float64, scale int, seed int64) Options {
+	level = min(max(level, 0), 1)
+
+	opts := Options{
+		Perspective: 0.12 * level,
+		Rotation:    180 * level,
+		Blur:        0.45 * level * float64(scale),
+		Noise:       40 * level,
+		Lighting:    0.6 * level,
+		Downscale:   1 + 0.4*level*float64(scale),
+		Seed:        seed,
+	}
+	if level > 0 {
+		opts.JPEGQuality = int(95 - 75*level)
+	}
+	return opts
+}
+
+// Apply distorts img. Transparent pixels are treated as white paper.
+// Geometry comes first, then lighting and optics, then the sensor (downscale,
+// noise) and finally compression.
+func Apply(img image.Image, opts Options) (*image.RGBA, error) {
+	rng := rand.New(rand.NewSource(opts.Seed))
+	buf := fromImage(img)
+
+	if opts.Perspective > 0 || opts.Rotation != 0 {
+		buf = buf.warp(opts.Perspective, opts.Rotation, rng)
+	}
+	if opts.Lighting > 0 {
+		buf.lighting(opts.Lighting, rng)
+	}
+	if opts.Blur > 0 {
+		buf = buf.blur(opts.Blur)
+	}
+	if opts.Downscale > 1 {
+		buf = buf.downscale(opts.Downscale)
+	}
+	if opts.Noise > 0 {
+		buf.noise(opts.Noise, rng)
+	}
+
+	out := buf.toImage()
+	if opts.JPEGQuality > 0 {
+		return recompress(out, min(opts.JPEGQuality, 100))
+	}
+	return out, nil
+}
+
+// RGB image with float channels (0-255), no alpha
+type buffer struct {
+	w, h int
+	pix  []float64
+}
+
+func newBuffer(w, h int) *buffer {
+	return &buffer{w: w, h: h, pix: make([]float64, w*h*3)}
+}
+
+func fromImage(img image.Image) *buffer {
+	bounds := img.Bounds()
+	buf := newBuffer(bounds.Dx(), bounds.Dy())
+
+	for y := range buf.h {
+		for x := range buf.w {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			i := (y*buf.w + x) * 3
+			// Premultiplied, so adding the missing alpha composites onto white
+			buf.pix[i] = float64(r+0xffff-a) / 257
+			buf.pix[i+1] = float64(g+0xffff-a) / 257
+			buf.pix[i+2] = float64(b+0xffff-a) / 257
+		}
+	}
+	return buf
+}
+
+func (buf *buffer) toImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, buf.w, buf.h))
+	for y := range buf.h {
+		for x := range buf.w {
+			i := (y*buf.w + x) * 3
+			o := img.PixOffset(x, y)
+			img.Pix[o] = clamp(buf.pix[i])
+			img.Pix[o+1] = clamp(buf.pix[i+1])
+			img.Pix[o+2] = clamp(buf.pix[i+2])
+			img.Pix[o+3] = 255
+		}
+	}
+	return img
+}
+
+// Bilinear sample, outside the image is white
+func (buf *buffer) sample(x, y float64, out []float64) {
+	x -= 0.5
+	y -= 0.5
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	fx, fy := x-float64(x0), y-float64(y0)
+
+	for c := range 3 {
+		out[c] = 0
+	}
+	for _, corner := range [4][3]float64{
+		{0, 0, (1 - fx) * (1 - fy)},
+		{1, 0, fx * (1 - fy)},
+		{0, 1, (1 - fx) * fy},
+		{1, 1, fx * fy},
+	} {
+		px, py := x0+int(corner[0]), y0+int(corner[1])
+		for c := range 3 {
+			val := 255.0
+			if px >= 0 && py >= 0 && px < buf.w && py < buf.h {
+				val = buf.pix[(py*buf.w+px)*3+c]
+			}
+			out[c] += val * corner[2]
+		}
+	}
+}
+
+// Moves every corner inwards by a random amount (perspective) and rotates the
+// result around the center. The canvas grows to fit the rotated image.
+func (buf *buffer) warp(perspective, rotation float64, rng *rand.Rand) *buffer {
+	w, h := float64(buf.w), float64(buf.h)
+	src := [4][2]float64{{0, 0}, {w, 0}, {w, h}, {0, h}}
+	inward := [4][2]float64{{1, 1}, {-1, 1}, {-1, -1}, {1, -1}}
+
+	angle := (rng.Float64()*2 - 1) * rotation * math.Pi / 180
+	sin, cos := math.Sincos(angle)
+
+	var dst [4][2]float64
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for i, p := range src {
+		x := p[0] + inward[i][0]*rng.Float64()*perspective*w
+		y := p[1] + inward[i][1]*rng.Float64()*perspective*h
+
+		x, y = x-w/2, y-h/2
+		x, y = x*cos-y*sin, x*sin+y*cos
+		dst[i] = [2]float64{x, y}
+
+		minX, maxX = min(minX, x), max(maxX, x)
+		minY, maxY = min(minY, y), max(maxY, y)
+	}
+	for i := range dst {
+		dst[i][0] -= minX
+		dst[i][1] -= minY
+	}
+
+	out := newBuffer(int(math.Ceil(maxX-minX)), int(math.Ceil(maxY-minY)))
+	toSrc := homography(dst, src)
+
+	px := make([]float64, 3)
+	for y := range out.h {
+		for x := range out.w {
+			sx, sy := toSrc.apply(float64(x)+0.5, float64(y)+0.5)
+			buf.sample(sx, sy, px)
+			copy(out.pix[(y*out.w+x)*3:], px)
+		}
+	}
+	return out
+}
+
+// A light source off to one side: brightness falls off linearly across the
+// image in a random direction.
+func (buf *buffer) lighting(strength float64, rng *rand.Rand) {
+	dirX, dirY := math.Sincos(rng.Float64() * 2 * math.Pi)
+	diag := math.Hypot(float64(buf.w), float64(buf.h)) / 2
+
+	for y := range buf.h {
+		for x := range buf.w {
+			dx, dy := float64(x)-float64(buf.w)/2, float64(y)-float64(buf.h)/2
+			t := (dx*dirX + dy*dirY) / diag // -1 .. 1
+			factor := 1 - strength*(t+1)/2
+
+			i := (y*buf.w + x) * 3
+			for c := range 3 {
+				buf.pix[i+c] *= factor
+			}
+		}
+	}
+}
+
+// Separable Gaussian blur, edges are extended
+func (buf *buffer) blur(sigma float64) *buffer {
+	radius := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*radius+1)
+	total := 0.0
+	for i := range kernel {
+		d := float64(i - radius)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		total += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= total
+	}
+
+	pass := func(in *buffer, dx, dy int) *buffer {
+		out := newBuffer(in.w, in.h)
+		for y := range in.h {
+			for x := range in.w {
+				o := (y*in.w + x) * 3
+				for k, weight := range kernel {
+					sx := min(max(x+(k-radius)*dx, 0), in.w-1)
+					sy := min(max(y+(k-radius)*dy, 0), in.h-1)
+					i := (sy*in.w + sx) * 3
+					out.pix[o] += in.pix[i] * weight
+					out.pix[o+1] += in.pix[i+1] * weight
+					out.pix[o+2] += in.pix[i+2] * weight
+				}
+			}
+		}
+		return out
+	}
+
+	return pass(pass(buf, 1, 0), 0, 1)
+}
+
+// Area average, every output pixel is the mean of the source pixels it covers
+func (buf *buffer) downscale(factor float64) *buffer {
+	out := newBuffer(max(1, int(float64(buf.w)/factor)), max(1, int(float64(buf.h)/factor)))
+	counts := make([]float64, out.w*out.h)
+
+	for y := range buf.h {
+		oy := min(int(float64(y)/factor), out.h-1)
+		for x := range buf.w {
+			ox := min(int(float64(x)/factor), out.w-1)
+			o := oy*out.w + ox
+			i := (y*buf.w + x) * 3
+			out.pix[o*3] += buf.pix[i]
+			out.pix[o*3+1] += buf.pix[i+1]
+			out.pix[o*3+2] += buf.pix[i+2]
+			counts[o]++
+		}
+	}
+
+	for o, n := range counts {
+		for c := range 3 {
+			out.pix[o*3+c] /= n
+		}
+	}
+	return out
+}
+
+// Gaussian sensor noise, same on all channels (luminance noise)
+func (buf *buffer) noise(sigma float64, rng *rand.Rand) {
+	for i := 0; i < len(buf.pix); i += 3 {
+		n := rng.NormFloat64() * sigma
+		buf.pix[i] += n
+		buf.pix[i+1] += n
+		buf.pix[i+2] += n
+	}
+}
+
+func recompress(img *image.RGBA, quality int) (*image.RGBA, error) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+
+	decoded, err := jpeg.Decode(&encoded)
+	if err != nil {
+		return nil, err
+	}
+	return fromImage(decoded).toImage(), nil
+}
+
+func clamp(v float64) uint8 {
+	return uint8(min(max(math.Round(v), 0), 255))
+}
+
+// 3x3 projective transform, h[2][2] is always 1
+type matrix [3][3]float64
+
+func (m matrix) apply(x, y float64) (float64, float64) {
+	w := m[2][0]*x + m[2][1]*y + m[2][2]
+	return (m[0][0]*x + m[0][1]*y + m[0][2]) / w, (m[1][0]*x + m[1][1]*y + m[1][2]) / w
+}
+
+// Solves for the transform that maps the 4 `from` points onto the `to` points
+// (Gaussian elimination on the usual 8x8 system).
+func homography(from, to [4][2]float64) matrix {
+	var a [8][9]float64
+	for i := range 4 {
+		x, y := from[i][0], from[i][1]
+		u, v := to[i][0], to[i][1]
+		a[2*i] = [9]float64{x, y, 1, 0, 0, 0, -x * u, -y * u, u}
+		a[2*i+1] = [9]float64{0, 0, 0, x, y, 1, -x * v, -y * v, v}
+	}
+
+	for col := range 8 {
+		pivot := col
+		for row := col + 1; row < 8; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+
+		for row := range 8 {
+			if row == col || a[col][col] == 0 {
+				continue
+			}
+			f := a[row][col] / a[col][col]
+			for k := col; k < 9; k++ {
+				a[row][k] -= f * a[col][k]
+			}
+		}
+	}
+
+	var h [8]float64
+	for i := range 8 {
+		h[i] = a[i][8] / a[i][i]
+	}
+	return matrix{{h[0], h[1], h[2]}, {h[3], h[4], h[5]}, {h[6], h[7], 1}}
+}
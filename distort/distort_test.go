@@ -0,0 +1,102 @@
+package distort
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// Black square in the middle of a white image
+func testImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 80))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(30, 20, 70, 60), image.Black, image.Point{}, draw.Src)
+	return img
+}
+
+func TestNoDistortion(t *testing.T) {
+	img := testImage()
+	out, err := Apply(img, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Pix, img.Pix) {
+		t.Fatal("expected the image to be unchanged without any distortion")
+	}
+}
+
+func TestSameSeedSameResult(t *testing.T) {
+	opts := Preset(0.7, 4, 123)
+
+	a, err := Apply(testImage(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := Apply(testImage(), opts)
+	if !bytes.Equal(a.Pix, b.Pix) {
+		t.Fatal("expected identical output for the same seed")
+	}
+
+	opts.Seed++
+	c, _ := Apply(testImage(), opts)
+	if bytes.Equal(a.Pix, c.Pix) {
+		t.Fatal("expected different output for a different seed")
+	}
+}
+
+func TestDownscale(t *testing.T) {
+	out, _ := Apply(testImage(), Options{Downscale: 2})
+	if out.Bounds().Dx() != 50 || out.Bounds().Dy() != 40 {
+		t.Fatalf("expected 50x40, got %v", out.Bounds())
+	}
+
+	// Inside the square stays black, the border stays white
+	if r, _, _, _ := out.At(25, 20).RGBA(); r != 0 {
+		t.Errorf("expected black at the center, got %d", r>>8)
+	}
+	if r, _, _, _ := out.At(2, 2).RGBA(); r>>8 != 255 {
+		t.Errorf("expected white at the border, got %d", r>>8)
+	}
+}
+
+func TestBlurKeepsAverage(t *testing.T) {
+	img := testImage()
+	out, _ := Apply(img, Options{Blur: 2})
+
+	mean := func(img *image.RGBA) float64 {
+		total := 0.0
+		for i := 0; i < len(img.Pix); i += 4 {
+			total += float64(img.Pix[i])
+		}
+		return total / float64(len(img.Pix)/4)
+	}
+
+	if diff := mean(out) - mean(img); diff > 1 || diff < -1 {
+		t.Fatalf("blur changed the average brightness by %.2f", diff)
+	}
+	if out.RGBAAt(30, 40) == (color.RGBA{0, 0, 0, 255}) {
+		t.Fatal("expected the edge of the square to be blurred")
+	}
+}
+
+func TestRotationGrowsCanvas(t *testing.T) {
+	out, _ := Apply(testImage(), Options{Rotation: 90, Seed: 7})
+	if out.Bounds().Dx() < 80 || out.Bounds().Dy() < 80 {
+		t.Fatalf("rotated image too small: %v", out.Bounds())
+	}
+}
+
+func TestHomography(t *testing.T) {
+	from := [4][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	to := [4][2]float64{{1, 2}, {20, 1}, {18, 25}, {3, 15}}
+	m := homography(from, to)
+
+	for i := range from {
+		x, y := m.apply(from[i][0], from[i][1])
+		if dx, dy := x-to[i][0], y-to[i][1]; dx*dx+dy*dy > 1e-12 {
+			t.Errorf("corner %d maps to (%.3f, %.3f), want %v", i, x, y, to[i])
+		}
+	}
+}
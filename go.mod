@@ -2,4 +2,4 @@ module aboutblank/qr-code
 
 go 1.24.2
 
-require golang.org/x/text v0.34.0 // indirect
+require golang.org/x/text v0.34.0
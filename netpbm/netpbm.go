@@ -0,0 +1,54 @@
+// Package netpbm writes binary PBM (1-bit) and PGM (8-bit gray) images, the
+// simplest raster formats there are. Transparency is flattened onto white.
+package netpbm
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// EncodePBM writes a P4 bitmap. Pixels darker than 50% gray are black.
+func EncodePBM(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "P4\n%d %d\n", bounds.Dx(), bounds.Dy())
+
+	// 8 pixels per byte, most significant bit first, 1 = black
+	row := make([]byte, (bounds.Dx()+7)/8)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		clear(row)
+		for i := range bounds.Dx() {
+			if gray(img.At(bounds.Min.X+i, y)) < 128 {
+				row[i/8] |= 1 << (7 - i%8)
+			}
+		}
+		out.Write(row)
+	}
+	return out.Flush()
+}
+
+// EncodePGM writes a P5 graymap with 255 as the maximum value
+func EncodePGM(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "P5\n%d %d\n255\n", bounds.Dx(), bounds.Dy())
+
+	row := make([]byte, bounds.Dx())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for i := range row {
+			row[i] = gray(img.At(bounds.Min.X+i, y))
+		}
+		out.Write(row)
+	}
+	return out.Flush()
+}
+
+// Luminance over a white background
+func gray(c color.Color) uint8 {
+	r, g, b, a := c.RGBA()
+	y := (19595*r + 38470*g + 7471*b + 1<<15) >> 16
+	return uint8(min(y+0xffff-a, 0xffff) >> 8)
+}
@@ -0,0 +1,42 @@
+package netpbm
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestEncodePBM(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 10, 2))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	img.SetGray(0, 0, color.Gray{0})
+	img.SetGray(9, 1, color.Gray{100})
+
+	var buf bytes.Buffer
+	if err := EncodePBM(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte("P4\n10 2\n"), 0x80, 0, 0, 0x40)
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("got %q, expected %q", buf.Bytes(), want)
+	}
+}
+
+func TestEncodePGM(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 1))
+	img.Set(0, 0, color.Black)
+	img.Set(1, 0, color.RGBA{0xff, 0, 0, 0xff})
+	// (2, 0) transparent: white
+
+	var buf bytes.Buffer
+	if err := EncodePGM(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte("P5\n3 1\n255\n"), 0, 76, 255)
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("got %q, expected %q", buf.Bytes(), want)
+	}
+}
@@ -0,0 +1,178 @@
+// Package pdf writes minimal PDF documents: pages with filled and stroked
+// paths in RGB or CMYK, nothing else. No fonts, no images, no compression.
+// Units are PostScript points (1/72 inch), the origin is the bottom-left corner.
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+const PointsPerMM = 72 / 25.4
+
+// Millimeters to points
+func MM(mm float64) float64 {
+	return mm * PointsPerMM
+}
+
+type Rect struct {
+	X, Y, W, H float64
+}
+
+// Grows the rectangle by d on every side
+func (r Rect) Outset(d float64) Rect {
+	return Rect{r.X - d, r.Y - d, r.W + 2*d, r.H + 2*d}
+}
+
+type Document struct {
+	pages []*Page
+}
+
+type Page struct {
+	MediaBox Rect
+	TrimBox  Rect // Final size after cutting, zero = same as the media box
+	BleedBox Rect // Zero = same as the media box
+
+	content bytes.Buffer
+}
+
+func New() *Document {
+	return &Document{}
+}
+
+func (d *Document) AddPage(width, height float64) *Page {
+	page := &Page{MediaBox: Rect{0, 0, width, height}}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// ===== Drawing =====
+
+func (p *Page) op(args ...any) {
+	for i, arg := range args {
+		if i > 0 {
+			p.content.WriteByte(' ')
+		}
+		if v, ok := arg.(float64); ok {
+			p.content.WriteString(num(v))
+		} else {
+			fmt.Fprint(&p.content, arg)
+		}
+	}
+	p.content.WriteByte('\n')
+}
+
+// Components 0-1
+func (p *Page) SetFillRGB(r, g, b float64)   { p.op(r, g, b, "rg") }
+func (p *Page) SetStrokeRGB(r, g, b float64) { p.op(r, g, b, "RG") }
+
+func (p *Page) SetFillCMYK(c, m, y, k float64)   { p.op(c, m, y, k, "k") }
+func (p *Page) SetStrokeCMYK(c, m, y, k float64) { p.op(c, m, y, k, "K") }
+
+func (p *Page) SetLineWidth(w float64) { p.op(w, "w") }
+
+func (p *Page) MoveTo(x, y float64) { p.op(x, y, "m") }
+func (p *Page) LineTo(x, y float64) { p.op(x, y, "l") }
+func (p *Page) ClosePath()          { p.op("h") }
+
+// Adds a rectangle to the current path
+func (p *Page) Rect(r Rect) { p.op(r.X, r.Y, r.W, r.H, "re") }
+
+// Fill (nonzero winding) or stroke the current path
+func (p *Page) Fill()   { p.op("f") }
+func (p *Page) Stroke() { p.op("S") }
+
+// ===== Output =====
+
+// Rounded to 1/1000 pt, without trailing zeros
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+func (r Rect) String() string {
+	return fmt.Sprintf("[%s %s %s %s]", num(r.X), num(r.Y), num(r.X+r.W), num(r.Y+r.H))
+}
+
+// Counts the bytes so the cross-reference table can point at every object
+type countingWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// WriteTo writes the whole document. Objects: 1 = catalog, 2 = page tree,
+// then a page and its content stream for every page.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		return 0, fmt.Errorf("document has no pages")
+	}
+
+	out := &countingWriter{w: bufio.NewWriter(w)}
+	offsets := []int64{0} // Object 0 is the head of the free list
+
+	begin := func() int {
+		offsets = append(offsets, out.n)
+		num := len(offsets) - 1
+		fmt.Fprintf(out, "%d 0 obj\n", num)
+		return num
+	}
+	end := func() {
+		fmt.Fprint(out, "endobj\n")
+	}
+
+	// Binary comment so transfer tools don't treat the file as text
+	fmt.Fprint(out, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	begin()
+	fmt.Fprint(out, "<< /Type /Catalog /Pages 2 0 R >>\n")
+	end()
+
+	begin()
+	fmt.Fprint(out, "<< /Type /Pages /Kids [")
+	for i := range d.pages {
+		if i > 0 {
+			fmt.Fprint(out, " ")
+		}
+		fmt.Fprintf(out, "%d 0 R", 3+2*i)
+	}
+	fmt.Fprintf(out, "] /Count %d >>\n", len(d.pages))
+	end()
+
+	for _, page := range d.pages {
+		num := begin()
+		fmt.Fprintf(out, "<< /Type /Page /Parent 2 0 R /MediaBox %s", page.MediaBox)
+		if page.TrimBox != (Rect{}) {
+			fmt.Fprintf(out, " /TrimBox %s", page.TrimBox)
+		}
+		if page.BleedBox != (Rect{}) {
+			fmt.Fprintf(out, " /BleedBox %s", page.BleedBox)
+		}
+		fmt.Fprintf(out, " /Resources << >> /Contents %d 0 R >>\n", num+1)
+		end()
+
+		begin()
+		fmt.Fprintf(out, "<< /Length %d >>\nstream\n", page.content.Len())
+		out.Write(page.content.Bytes())
+		fmt.Fprint(out, "\nendstream\n")
+		end()
+	}
+
+	xref := out.n
+	fmt.Fprintf(out, "xref\n0 %d\n", len(offsets))
+	fmt.Fprint(out, "0000000000 65535 f \n")
+	for _, offset := range offsets[1:] {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets), xref)
+
+	return out.n, out.w.Flush()
+}
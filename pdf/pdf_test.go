@@ -0,0 +1,68 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDocumentStructure(t *testing.T) {
+	doc := New()
+	for i := range 3 {
+		page := doc.AddPage(100, 200)
+		page.TrimBox = Rect{10, 10, 80, 180}
+		page.SetFillCMYK(0, 0, 0, 1)
+		page.Rect(Rect{float64(i), 0, 10.5, 1.0 / 3})
+		page.Fill()
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	if !strings.HasPrefix(out, "%PDF-1.4\n") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Fatal("missing header or trailer")
+	}
+	if !strings.Contains(out, "/Count 3") || !strings.Contains(out, "/TrimBox [10 10 90 190]") {
+		t.Error("page tree or trim box missing")
+	}
+	if !strings.Contains(out, "2 0 10.5 0.333 re") {
+		t.Error("numbers should be rounded to 1/1000 pt")
+	}
+
+	// startxref points at the table, the table at every object
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(out)
+	xref, _ := strconv.Atoi(m[1])
+	if !strings.HasPrefix(out[xref:], "xref\n") {
+		t.Fatal("startxref doesn't point at the xref table")
+	}
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(out[xref:], -1)
+	if len(entries) != 2+2*3 {
+		t.Fatalf("%d objects in the xref table, expected 8", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[1])
+		if !strings.HasPrefix(out[offset:], fmt.Sprintf("%d 0 obj\n", i+1)) {
+			t.Errorf("xref entry %d points at %q", i+1, out[offset:offset+10])
+		}
+	}
+
+	// Stream lengths
+	for _, m := range regexp.MustCompile(`(?s)<< /Length (\d+) >>\nstream\n(.*?)\nendstream`).FindAllStringSubmatch(out, -1) {
+		if n, _ := strconv.Atoi(m[1]); n != len(m[2]) {
+			t.Errorf("stream length %d, actual %d", n, len(m[2]))
+		}
+	}
+}
+
+func TestEmptyDocument(t *testing.T) {
+	if _, err := New().WriteTo(&bytes.Buffer{}); err == nil {
+		t.Error("expected an error for a document without pages")
+	}
+}
@@ -0,0 +1,113 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Lowest contrast CheckColors accepts, the threshold for grade C
+var minColorContrast = contrastGrades[Grade_A-Grade_C]
+
+// ColorContrast is the symbol contrast (background minus foreground
+// reflectance, in percent) that fg on bg gives, graded like GradeImage does.
+// Transparency is judged over white, the way the decoder sees it. Negative
+// means the colors are inverted.
+func ColorContrast(fg, bg color.Color) Measurement {
+	contrast := colorReflectance(bg) - colorReflectance(fg)
+	return Measurement{Value: contrast, Grade: gradeAtLeast(contrast, contrastGrades)}
+}
+
+// CheckColors fails when fg on bg is inverted (light modules on a dark
+// background) or has less than grade C contrast, both break many scanners.
+// A transparent background is judged as white, whatever ends up behind it
+// has to be light enough too.
+func CheckColors(fg, bg color.Color) error {
+	contrast := ColorContrast(fg, bg)
+	if contrast.Value < 0 {
+		return fmt.Errorf("colors are inverted, the foreground is lighter than the background and many scanners can't read that")
+	}
+	if contrast.Value < minColorContrast {
+		return fmt.Errorf("contrast between foreground and background is %.0f%% (grade %s), at least %.0f%% is needed",
+			contrast.Value, contrast.Grade, minColorContrast)
+	}
+	return nil
+}
+
+// In percent, same weights as the decoder's luminance
+func colorReflectance(c color.Color) float64 {
+	r, g, b, a := c.RGBA()
+	l := (19595*r+38470*g+7471*b+1<<15)>>16 + (0xffff - a)
+	return float64(min(l, 0xffff)) * 100 / 0xffff
+}
+
+// Check is CheckColors for what opts render: the fill against the background
+// if there is one, the foreground otherwise. Invert is a deliberate choice,
+// so the colors are checked before swapping them.
+func (opts ImageOptions) Check() error {
+	bg := rgba(opts.Background, color.White)
+	if opts.Fill != nil && opts.Painter == nil {
+		return CheckFill(opts.Fill, bg)
+	}
+	return CheckColors(rgba(opts.Foreground, color.Black), bg)
+}
+
+// GenerateCheckedImage is GenerateImageWithOptions that refuses colors that
+// won't scan, see ImageOptions.Check.
+func (qr *QRCode) GenerateCheckedImage(opts ImageOptions) (*image.RGBA, error) {
+	if err := opts.Check(); err != nil {
+		return nil, err
+	}
+	return qr.GenerateImageWithOptions(opts), nil
+}
+
+// Check is ImageOptions.Check for SVG. Only colors given as #rgb, #rrggbb,
+// #rrggbbaa or "none" can be measured, other CSS colors are an error.
+func (opts SVGOptions) Check() error {
+	bg, err := cssColor(opts.Background, color.White)
+	if err != nil {
+		return err
+	}
+	if opts.Fill != nil {
+		return CheckFill(opts.Fill, bg)
+	}
+	fg, err := cssColor(opts.Foreground, color.Black)
+	if err != nil {
+		return err
+	}
+	return CheckColors(fg, bg)
+}
+
+// WriteCheckedSVG is WriteSVG that refuses colors that won't scan, see
+// SVGOptions.Check.
+func (qr *QRCode) WriteCheckedSVG(w io.Writer, opts SVGOptions) error {
+	if err := opts.Check(); err != nil {
+		return err
+	}
+	return qr.WriteSVG(w, opts)
+}
+
+func cssColor(s string, fallback color.Color) (color.Color, error) {
+	if s == "" {
+		return fallback, nil
+	}
+	if s == "none" || strings.EqualFold(s, "transparent") {
+		return color.Transparent, nil
+	}
+
+	hex, ok := strings.CutPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if !ok || err != nil || len(hex) != 8 {
+		return nil, fmt.Errorf("can't check the contrast of color %q, only #rgb, #rrggbb, #rrggbbaa and none", s)
+	}
+	return color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
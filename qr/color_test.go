@@ -0,0 +1,87 @@
+package qr
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+)
+
+func TestCheckColors(t *testing.T) {
+	navy := color.RGBA{0x1a, 0x23, 0x7e, 0xff}
+	cases := []struct {
+		name   string
+		fg, bg color.Color
+		ok     bool
+	}{
+		{"black on white", color.Black, color.White, true},
+		{"navy on cream", navy, color.RGBA{0xff, 0xf8, 0xe1, 0xff}, true},
+		{"black on transparent", color.Black, color.Transparent, true},
+		{"inverted", color.White, navy, false},
+		{"light gray on white", color.Gray{0xc0}, color.White, false},
+		{"yellow on white", color.RGBA{0xff, 0xeb, 0x3b, 0xff}, color.White, false},
+	}
+
+	for _, c := range cases {
+		if err := CheckColors(c.fg, c.bg); (err == nil) != c.ok {
+			t.Errorf("%s: got %v", c.name, err)
+		}
+	}
+
+	if m := ColorContrast(color.Black, color.White); m.Value != 100 || m.Grade != Grade_A {
+		t.Errorf("black on white: %.1f%% grade %s, expected 100%% grade A", m.Value, m.Grade)
+	}
+}
+
+func TestGenerateImageColors(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/colors", EC_Medium, 0, false)
+	fg := color.RGBA{0x1a, 0x23, 0x7e, 0xff}
+
+	img := qrCode.GenerateImageWithOptions(ImageOptions{Scale: 4, Foreground: fg, Background: color.Transparent})
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{}) {
+		t.Errorf("quiet zone is %v, expected transparent", got)
+	}
+	// Top left finder corner
+	if got := img.RGBAAt(4*4, 4*4); got != fg {
+		t.Errorf("dark module is %v, expected %v", got, fg)
+	}
+
+	if err := VerifyImage(img, []byte("https://example.com/colors")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheckedRendering(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/colors", EC_Medium, 0, false)
+	yellow := color.RGBA{0xff, 0xeb, 0x3b, 0xff}
+
+	if _, err := qrCode.GenerateCheckedImage(ImageOptions{Scale: 2, Foreground: yellow}); err == nil {
+		t.Error("yellow on white should be refused")
+	}
+	if _, err := qrCode.GenerateCheckedImage(ImageOptions{Scale: 2, Foreground: color.White, Background: color.Black}); err == nil {
+		t.Error("inverted colors should be refused")
+	}
+	// Asking for Invert is fine, the colors themselves are
+	if img, err := qrCode.GenerateCheckedImage(ImageOptions{Scale: 2, Invert: true}); err != nil || img == nil {
+		t.Errorf("plain inverted image refused: %v", err)
+	}
+
+	var buf bytes.Buffer
+	for _, c := range []struct {
+		opts SVGOptions
+		ok   bool
+	}{
+		{SVGOptions{}, true},
+		{SVGOptions{Foreground: "#1a237e", Background: "none"}, true},
+		{SVGOptions{Foreground: "#ffeb3b"}, false},
+		{SVGOptions{Foreground: "#fff", Background: "#000"}, false},
+		{SVGOptions{Foreground: "navy"}, false}, // Can't be measured
+	} {
+		buf.Reset()
+		if err := qrCode.WriteCheckedSVG(&buf, c.opts); (err == nil) != c.ok {
+			t.Errorf("%+v: got %v", c.opts, err)
+		}
+		if !c.ok && buf.Len() != 0 {
+			t.Errorf("%+v: refused but wrote %d bytes", c.opts, buf.Len())
+		}
+	}
+}
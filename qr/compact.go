@@ -0,0 +1,203 @@
+package qr
+
+import (
+	"bufio"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// Where the symbol lands in a plain two color image
+type layout struct {
+	quietZone, scale int
+	side             int // Width and height in pixels
+	offset           int // Extra background left/top of the quiet zone, for Center
+}
+
+func (qr *QRCode) layout(opts ImageOptions) layout {
+	l := layout{quietZone: quietZoneOf(opts.QuietZone), scale: opts.Scale}
+
+	grid := qr.size + 2*l.quietZone
+	if opts.Size > 0 {
+		l.scale = max(opts.Size/grid, 1)
+	}
+	l.side = grid * l.scale
+	if opts.Size > 0 && opts.Center && l.side < opts.Size {
+		l.offset = (opts.Size - l.side) / 2
+		l.side = opts.Size
+	}
+	return l
+}
+
+func (l layout) dark(qr *QRCode, px, py int) bool {
+	px, py = px-l.offset, py-l.offset
+	if px < 0 || py < 0 {
+		return false
+	}
+	return qr.At(px/l.scale-l.quietZone, py/l.scale-l.quietZone)
+}
+
+// TwoColor tells if opts render with just the two colors, so PalettedImage,
+// GrayImage and WritePNG give the same image as GenerateImageWithOptions.
+// Logos, fills, painters, styles and smooth scaling (including a Size too
+// small for a pixel per module) need more.
+func (qr *QRCode) TwoColor(opts ImageOptions) bool {
+	return qr.logo == nil && opts.Fill == nil && opts.Painter == nil &&
+		squareStyle(opts.ModuleStyle, opts.FinderStyle) && !qr.smooth(opts)
+}
+
+// PalettedImage renders the symbol with one byte per pixel instead of four,
+// palette index Index_Light or Index_Dark. Only the options that keep it at
+// two colors are used: scale, quiet zone, size (not Smooth), colors and Invert.
+// A Size too small for a pixel per module gets 1 anyway, check TwoColor first
+// to get exactly what GenerateImageWithOptions renders.
+func (qr *QRCode) PalettedImage(opts ImageOptions) *image.Paletted {
+	light, dark := opts.colors()
+	l := qr.layout(opts)
+	img := image.NewPaletted(image.Rect(0, 0, l.side, l.side), color.Palette{light, dark})
+	for y := range l.side {
+		row := img.Pix[y*img.Stride : y*img.Stride+l.side]
+		for x := range row {
+			if l.dark(qr, x, y) {
+				row[x] = Index_Dark
+			}
+		}
+	}
+	return img
+}
+
+// GrayImage is PalettedImage as 8-bit gray, the colors turned into their
+// luminance. Transparency is lost.
+func (qr *QRCode) GrayImage(opts ImageOptions) *image.Gray {
+	light, dark := opts.colors()
+	lightGray, darkGray := color.GrayModel.Convert(light).(color.Gray).Y, color.GrayModel.Convert(dark).(color.Gray).Y
+	l := qr.layout(opts)
+	img := image.NewGray(image.Rect(0, 0, l.side, l.side))
+	for y := range l.side {
+		row := img.Pix[y*img.Stride : y*img.Stride+l.side]
+		for x := range row {
+			row[x] = lightGray
+			if l.dark(qr, x, y) {
+				row[x] = darkGray
+			}
+		}
+	}
+	return img
+}
+
+// ===== Streaming PNG =====
+
+// Uncompressed bytes per IDAT chunk
+const pngChunkSize = 1 << 15
+
+// WritePNG writes what GenerateImageWithOptions would render as a PNG. Two
+// color images are streamed row by row as a 1-bit PNG, only one row is ever
+// in memory: grayscale for black on white, a 2 color palette otherwise.
+// Anything else (logos, fills, styles, ...) is rendered and encoded normally.
+func (qr *QRCode) WritePNG(w io.Writer, opts ImageOptions) error {
+	if opts.Size <= 0 && opts.Scale <= 0 {
+		return fmt.Errorf("scale must be positive, got %d", opts.Scale)
+	}
+	if !qr.TwoColor(opts) {
+		return png.Encode(w, qr.GenerateImageWithOptions(opts))
+	}
+
+	light, dark := opts.colors()
+	l := qr.layout(opts)
+	out := bufio.NewWriter(w)
+
+	// Grayscale 0 is black and 1 white, palette indices match Index_Light/Dark
+	gray := light == color.RGBA{255, 255, 255, 255} && dark == color.RGBA{0, 0, 0, 255}
+	bit := func(isDark bool) byte {
+		if gray != isDark {
+			return 1
+		}
+		return 0
+	}
+
+	out.WriteString("\x89PNG\r\n\x1a\n")
+	header := make([]byte, 13)
+	binary.BigEndian.PutUint32(header[0:], uint32(l.side))
+	binary.BigEndian.PutUint32(header[4:], uint32(l.side))
+	header[8] = 1 // Bit depth
+	header[9] = 0 // Grayscale
+	if !gray {
+		header[9] = 3 // Palette
+	}
+	writePNGChunk(out, "IHDR", header)
+
+	if !gray {
+		palette, alpha := make([]byte, 0, 6), make([]byte, 0, 2)
+		for _, c := range []color.RGBA{light, dark} {
+			n := color.NRGBAModel.Convert(c).(color.NRGBA)
+			palette = append(palette, n.R, n.G, n.B)
+			alpha = append(alpha, n.A)
+		}
+		writePNGChunk(out, "PLTE", palette)
+		if alpha[0] != 255 || alpha[1] != 255 {
+			writePNGChunk(out, "tRNS", alpha)
+		}
+	}
+
+	idat := &pngChunkWriter{w: out}
+	z := zlib.NewWriter(idat)
+	// Filter type 0, then 8 pixels per byte, most significant bit first
+	row := make([]byte, 1+(l.side+7)/8)
+	for y := range l.side {
+		clear(row)
+		for x := range l.side {
+			row[1+x/8] |= bit(l.dark(qr, x, y)) << (7 - x%8)
+		}
+		if _, err := z.Write(row); err != nil {
+			return err
+		}
+	}
+	if err := z.Close(); err != nil {
+		return err
+	}
+	idat.flush()
+
+	writePNGChunk(out, "IEND", nil)
+	return out.Flush()
+}
+
+func writePNGChunk(w *bufio.Writer, kind string, data []byte) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(len(data)))
+	w.Write(buf[:])
+	w.WriteString(kind)
+	w.Write(data)
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(kind))
+	crc.Write(data)
+	binary.BigEndian.PutUint32(buf[:], crc.Sum32())
+	w.Write(buf[:])
+}
+
+// Cuts the zlib stream into IDAT chunks
+type pngChunkWriter struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+func (c *pngChunkWriter) Write(p []byte) (int, error) {
+	c.buf = append(c.buf, p...)
+	for len(c.buf) >= pngChunkSize {
+		writePNGChunk(c.w, "IDAT", c.buf[:pngChunkSize])
+		c.buf = c.buf[pngChunkSize:]
+	}
+	return len(p), nil
+}
+
+func (c *pngChunkWriter) flush() {
+	if len(c.buf) > 0 {
+		writePNGChunk(c.w, "IDAT", c.buf)
+		c.buf = nil
+	}
+}
@@ -0,0 +1,102 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestCompactImagesMatchGenerateImage(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/compact", EC_Medium, 0, false)
+
+	for _, opts := range []ImageOptions{
+		{Scale: 3},
+		{Scale: 2, QuietZone: QuietZone(0), Invert: true},
+		{Size: 200, Center: true, Foreground: color.RGBA{0x1a, 0x23, 0x7e, 0xff}},
+		{Scale: 2, Background: color.Transparent},
+	} {
+		want := qrCode.GenerateImageWithOptions(opts)
+
+		var buf bytes.Buffer
+		if err := qrCode.WritePNG(&buf, opts); err != nil {
+			t.Fatal(err)
+		}
+		streamed, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatalf("%+v: streamed PNG doesn't decode: %v", opts, err)
+		}
+
+		for name, img := range map[string]image.Image{
+			"paletted": qrCode.PalettedImage(opts),
+			"streamed": streamed,
+		} {
+			if img.Bounds() != want.Bounds() {
+				t.Fatalf("%+v %s: bounds %v, expected %v", opts, name, img.Bounds(), want.Bounds())
+			}
+			if !sameImage(img, want) {
+				t.Errorf("%+v %s: pixels differ from GenerateImageWithOptions", opts, name)
+			}
+		}
+	}
+
+	gray := qrCode.GrayImage(ImageOptions{Scale: 3})
+	if !sameImage(gray, qrCode.GenerateImage(3)) {
+		t.Error("gray image differs from GenerateImage")
+	}
+}
+
+func TestWritePNGIsSmall(t *testing.T) {
+	content := strings.Repeat("compact ", 200)
+	qrCode := GenerateQRCode(content, EC_Low, 0, false)
+	opts := ImageOptions{Scale: 10}
+
+	var full, compact bytes.Buffer
+	if err := png.Encode(&full, qrCode.GenerateImageWithOptions(opts)); err != nil {
+		t.Fatal(err)
+	}
+	if err := qrCode.WritePNG(&compact, opts); err != nil {
+		t.Fatal(err)
+	}
+	if compact.Len()*4 > full.Len() {
+		t.Errorf("1-bit PNG is %d bytes, RGBA PNG %d: expected a lot smaller", compact.Len(), full.Len())
+	}
+	if err := VerifyImage(decodePNG(t, compact.Bytes()), []byte(content)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWritePNGBadScale(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/compact", EC_Medium, 0, false)
+
+	for _, opts := range []ImageOptions{{}, {Scale: -2}, {Scale: -2, ModuleStyle: ModuleStyle_Circle}} {
+		var buf bytes.Buffer
+		if err := qrCode.WritePNG(&buf, opts); err == nil {
+			t.Errorf("%+v: expected an error, wrote %d bytes", opts, buf.Len())
+		}
+	}
+}
+
+func sameImage(a, b image.Image) bool {
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := a.At(x, y).RGBA()
+			r2, g2, b2, a2 := b.At(x, y).RGBA()
+			if r1>>8 != r2>>8 || g1>>8 != g2>>8 || b1>>8 != b2>>8 || a1>>8 != a2>>8 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func decodePNG(t *testing.T, data []byte) image.Image {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
@@ -0,0 +1,543 @@
+package qr
+
+import (
+	"aboutblank/qr-code/bitreader"
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+var (
+	ErrNotFound    = errors.New("no QR code found")
+	ErrUndecodable = errors.New("QR code found but could not be decoded")
+)
+
+// Point in image coordinates (pixels)
+type Point struct {
+	X, Y float64
+}
+
+// A single mode segment of a decoded symbol
+type Segment struct {
+	Mode      EncodingMode
+	CharCount int
+	Data      []byte // Kanji segments are converted to UTF-8
+	Bits      int    // Bits used, including the mode and char count indicators
+}
+
+type DecodeResult struct {
+	Version  Version
+	EcLevel  ErrorCorrectionLevel
+	Mask     int
+	Segments []Segment
+	Data     []byte // All segments concatenated
+
+	// Codewords corrected by Reed-Solomon, one entry per block (in block order)
+	ErrorsCorrected []int
+
+	// Codewords that were treated as erasures, one entry per block.
+	// Only set when decoding with DecodeOptions.Erasures.
+	Erasures []int
+
+	// Data codewords after error correction, de-interleaved (pad bytes included)
+	DataCodewords []byte
+
+	// Top-left, top-right, bottom-right, bottom-left corners of the symbol.
+	// Only set when decoding from an image.
+	Corners [4]Point
+
+	// Which variant of the symbol decoded
+	Inverted bool // Light modules on a dark background
+	Mirrored bool // Seen from behind, e.g. through glass (module matrix is transposed)
+
+	// Module space -> image space, for the sampled (not yet un-mirrored) grid
+	transform perspective
+}
+
+func (r *DecodeResult) Text() string {
+	return string(r.Data)
+}
+
+type DecodeOptions struct {
+	// Modules known to be unreadable, e.g. covered by a logo. Indexed [x][y]
+	// like the module matrix, true = bad. The codewords they belong to are
+	// corrected as erasures, which costs half as much EC as an unknown error.
+	// DecodeGridWithOptions fails if the mask isn't the size of the grid,
+	// DecodeImageWithOptions only applies it to symbols of the mask's size.
+	Erasures [][]bool
+}
+
+// DecodeGrid decodes a sampled module grid, indexed [x][y] like the module matrix.
+// true means a dark module.
+//
+// If the grid doesn't decode as-is, it is retried mirrored (transposed),
+// inverted and both. The result says which variant worked.
+func DecodeGrid(grid [][]bool) (*DecodeResult, error) {
+	return DecodeGridWithOptions(grid, DecodeOptions{})
+}
+
+func DecodeGridWithOptions(grid [][]bool, opts DecodeOptions) (*DecodeResult, error) {
+	erasures := opts.Erasures
+	if erasures != nil && len(erasures) != len(grid) {
+		return nil, fmt.Errorf("erasure mask has %d columns, the grid %d", len(erasures), len(grid))
+	}
+	for _, column := range erasures {
+		if len(column) != len(grid) {
+			return nil, fmt.Errorf("erasure mask is not square")
+		}
+	}
+
+	result, err := decodeGrid(grid, erasures)
+	if err == nil {
+		return result, nil
+	}
+
+	for _, variant := range [][2]bool{{false, true}, {true, false}, {true, true}} {
+		inverted, mirrored := variant[0], variant[1]
+		variantErasures := erasures
+		if erasures != nil {
+			variantErasures = gridVariant(erasures, false, mirrored)
+		}
+		if r, err := decodeGrid(gridVariant(grid, inverted, mirrored), variantErasures); err == nil {
+			r.Inverted = inverted
+			r.Mirrored = mirrored
+			return r, nil
+		}
+	}
+
+	// Report why the grid as given failed, that's the most useful error
+	return nil, err
+}
+
+func gridVariant(grid [][]bool, inverted, mirrored bool) [][]bool {
+	size := len(grid)
+	out := make([][]bool, size)
+	for x := range out {
+		out[x] = make([]bool, size)
+		for y := range out[x] {
+			if mirrored {
+				out[x][y] = grid[y][x] != inverted
+			} else {
+				out[x][y] = grid[x][y] != inverted
+			}
+		}
+	}
+	return out
+}
+
+func decodeGrid(grid [][]bool, erasures [][]bool) (*DecodeResult, error) {
+	size := len(grid)
+	for _, column := range grid {
+		if len(column) != size {
+			return nil, fmt.Errorf("%w: grid is not square", ErrUndecodable)
+		}
+	}
+	if size < 21 || size > 177 || (size-17)%4 != 0 {
+		return nil, fmt.Errorf("%w: invalid symbol size %d", ErrUndecodable, size)
+	}
+	version := Version((size - 17) / 4)
+
+	tmpl := newTemplate(version)
+	ecLevel, mask, ok := tmpl.readFormatInfo(grid)
+	if !ok {
+		return nil, fmt.Errorf("%w: unreadable format information", ErrUndecodable)
+	}
+
+	if version >= 7 {
+		if v, ok := tmpl.readVersionInfo(grid); ok && v != version {
+			return nil, fmt.Errorf("%w: version info says %d but symbol is version %d", ErrUndecodable, v, version)
+		}
+	}
+
+	ecInfo := getEcInfo(version, ecLevel)
+	codewords := tmpl.readCodewords(grid, mask, ecInfo.TotalCodewords())
+
+	blocks := deinterleave(codewords, ecInfo)
+	maxErrors := correctionCapacity(version, ecLevel)
+	blockErasures := tmpl.erasedCodewords(erasures, ecInfo)
+
+	result := &DecodeResult{
+		Version: version,
+		EcLevel: ecLevel,
+		Mask:    mask,
+	}
+
+	data := make([]byte, 0, ecInfo.TotalDataCodewords)
+	for i, block := range blocks {
+		n, err := correctBlock(block, ecInfo.ECCodewordsPerBlock, maxErrors, blockErasures[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: block %d: %v", ErrUndecodable, i, err)
+		}
+		result.ErrorsCorrected = append(result.ErrorsCorrected, n)
+		if erasures != nil {
+			result.Erasures = append(result.Erasures, len(blockErasures[i]))
+		}
+		data = append(data, block[:len(block)-ecInfo.ECCodewordsPerBlock]...)
+	}
+
+	segments, _, err := parseSegments(data, version)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUndecodable, err)
+	}
+
+	result.DataCodewords = data
+	result.Segments = segments
+	for _, seg := range segments {
+		result.Data = append(result.Data, seg.Data...)
+	}
+
+	if verbose {
+		fmt.Printf("Decoded version %d, EC %s, mask %d\n", version, getErrorCorrectionString(ecLevel), mask)
+	}
+	return result, nil
+}
+
+// Creates an empty symbol with all function patterns in place,
+// so dataPositions() and the format/version positions can be used for reading.
+func newTemplate(version Version) *QRCode {
+	tmpl := New(version, EC_Low)
+	tmpl.addFunctionPatterns()
+	return tmpl
+}
+
+// Reads both copies of the format information and picks the closest valid
+// codeword. Up to 3 bit errors are tolerated (format info is a BCH(15,5) code).
+func (qr *QRCode) readFormatInfo(grid [][]bool) (ErrorCorrectionLevel, int, bool) {
+	bestDist := 16
+	var bestLevel ErrorCorrectionLevel
+	bestMask := 0
+
+	for copyIdx := range 2 {
+		var read uint16
+		for i, pos := range qr.formatPositions[copyIdx*15 : copyIdx*15+15] {
+			if grid[pos[0]][pos[1]] {
+				read |= 1 << (14 - i)
+			}
+		}
+
+		for level := range formatInfo {
+			for mask, info := range formatInfo[level] {
+				dist := bits.OnesCount16(read ^ info)
+				if dist < bestDist {
+					bestDist = dist
+					bestLevel = ErrorCorrectionLevel(level)
+					bestMask = mask
+				}
+			}
+		}
+	}
+
+	return bestLevel, bestMask, bestDist <= 3
+}
+
+// Reads both copies of the version information (versions 7+).
+// Up to 3 bit errors are tolerated (version info is a BCH(18,6) code).
+func (qr *QRCode) readVersionInfo(grid [][]bool) (Version, bool) {
+	bestDist := 19
+	bestVersion := Version(0)
+
+	for copyIdx := range 2 {
+		var read uint32
+		for i, pos := range qr.versionPositions[copyIdx*18 : copyIdx*18+18] {
+			if grid[pos[0]][pos[1]] {
+				read |= 1 << i
+			}
+		}
+
+		for v, info := range versionInfo {
+			dist := bits.OnesCount32(read ^ info)
+			if dist < bestDist {
+				bestDist = dist
+				bestVersion = Version(v)
+			}
+		}
+	}
+
+	return bestVersion, bestDist <= 3
+}
+
+// Reads the unmasked codewords in placement order. Remainder bits are ignored.
+func (qr *QRCode) readCodewords(grid [][]bool, mask int, count int) []byte {
+	codewords := make([]byte, count)
+	positions := qr.dataPositions()
+
+	for i := range count * 8 {
+		x, y := positions[i][0], positions[i][1]
+		if grid[x][y] != maskApplies(mask, x, y) {
+			codewords[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return codewords
+}
+
+// interleaveOrder is the inverse of the interleaving done in getFinalMessage.
+// For every codeword of the final message it returns the block it belongs to
+// and its index inside that block (data codewords first, then EC codewords).
+func interleaveOrder(ecInfo ErrorCorrectionInfo) [][2]int {
+	numBlocks := ecInfo.TotalBlocks()
+	dataLen := func(block int) int {
+		if block < ecInfo.Group1.Blocks {
+			return ecInfo.Group1.DataCodewords
+		}
+		return ecInfo.Group2.DataCodewords
+	}
+
+	maxDataLen := max(ecInfo.Group1.DataCodewords, ecInfo.Group2.DataCodewords)
+	order := make([][2]int, 0, ecInfo.TotalCodewords())
+
+	for i := range maxDataLen {
+		for block := range numBlocks {
+			if i < dataLen(block) {
+				order = append(order, [2]int{block, i})
+			}
+		}
+	}
+
+	for i := range ecInfo.ECCodewordsPerBlock {
+		for block := range numBlocks {
+			order = append(order, [2]int{block, dataLen(block) + i})
+		}
+	}
+
+	return order
+}
+
+// Maps a module mask to the codewords it touches, as indices into each RS
+// block. A codeword is erased as soon as one of its 8 modules is.
+func (qr *QRCode) erasedCodewords(modules [][]bool, ecInfo ErrorCorrectionInfo) [][]int {
+	blocks := make([][]int, ecInfo.TotalBlocks())
+	if modules == nil {
+		return blocks
+	}
+
+	positions := qr.dataPositions()
+	for i, pos := range interleaveOrder(ecInfo) {
+		for _, p := range positions[i*8 : i*8+8] {
+			if modules[p[0]][p[1]] {
+				blocks[pos[0]] = append(blocks[pos[0]], pos[1])
+				break
+			}
+		}
+	}
+	return blocks
+}
+
+// Splits the final message back into its RS blocks (data + EC codewords each)
+func deinterleave(codewords []byte, ecInfo ErrorCorrectionInfo) [][]byte {
+	blocks := make([][]byte, ecInfo.TotalBlocks())
+	for i := range blocks {
+		dataLen := ecInfo.Group1.DataCodewords
+		if i >= ecInfo.Group1.Blocks {
+			dataLen = ecInfo.Group2.DataCodewords
+		}
+		blocks[i] = make([]byte, dataLen+ecInfo.ECCodewordsPerBlock)
+	}
+
+	for i, pos := range interleaveOrder(ecInfo) {
+		blocks[pos[0]][pos[1]] = codewords[i]
+	}
+	return blocks
+}
+
+// Also returns how many bits were used, up to (not including) the terminator
+func parseSegments(data []byte, version Version) ([]Segment, int, error) {
+	reader := bitreader.New(data)
+	var segments []Segment
+	var used int
+
+	for {
+		used = len(data)*8 - reader.Remaining()
+		if reader.Remaining() < 4 {
+			break // No room left for a terminator
+		}
+
+		indicator := reader.ReadUInt(4)
+		if indicator == 0 {
+			break // Terminator
+		}
+
+		var mode EncodingMode
+		switch indicator {
+		case 0b0001:
+			mode = Encode_Numeric
+		case 0b0010:
+			mode = Encode_Alphanumeric
+		case 0b0100:
+			mode = Encode_Byte
+		case 0b1000:
+			mode = Encode_Kanji
+		case 0b0111: // ECI, only the designator is skipped. Data is returned as-is.
+			if err := skipECI(reader); err != nil {
+				return nil, 0, err
+			}
+			continue
+		case 0b0011: // Structured append header: symbol index, count and parity
+			if reader.Remaining() < 16 {
+				return nil, 0, fmt.Errorf("truncated structured append header")
+			}
+			reader.ReadUInt(16)
+			continue
+		case 0b0101: // FNC1 in first position
+			continue
+		case 0b1001: // FNC1 in second position, followed by the application indicator
+			if reader.Remaining() < 8 {
+				return nil, 0, fmt.Errorf("truncated FNC1 application indicator")
+			}
+			reader.ReadUInt(8)
+			continue
+		default:
+			return nil, 0, fmt.Errorf("invalid mode indicator %04b", indicator)
+		}
+
+		countSize := getCharCountSize(version, mode)
+		if reader.Remaining() < countSize {
+			return nil, 0, fmt.Errorf("truncated char count indicator")
+		}
+		before := reader.Remaining()
+		charCount := int(reader.ReadUInt(uint8(countSize)))
+
+		segData, err := readSegmentData(reader, mode, charCount)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		segments = append(segments, Segment{
+			Mode:      mode,
+			CharCount: charCount,
+			Data:      segData,
+			Bits:      4 + before - reader.Remaining(),
+		})
+	}
+
+	return segments, used, nil
+}
+
+func skipECI(reader *bitreader.BitReader) error {
+	if reader.Remaining() < 8 {
+		return fmt.Errorf("truncated ECI designator")
+	}
+
+	first := reader.ReadUInt(8)
+	extra := 0
+	switch {
+	case first&0x80 == 0:
+		extra = 0
+	case first&0xC0 == 0x80:
+		extra = 8
+	case first&0xE0 == 0xC0:
+		extra = 16
+	default:
+		return fmt.Errorf("invalid ECI designator")
+	}
+
+	if reader.Remaining() < extra {
+		return fmt.Errorf("truncated ECI designator")
+	}
+	reader.ReadUInt(uint8(extra))
+	return nil
+}
+
+func readSegmentData(reader *bitreader.BitReader, mode EncodingMode, charCount int) ([]byte, error) {
+	switch mode {
+	case Encode_Numeric:
+		return readNumericData(reader, charCount)
+	case Encode_Alphanumeric:
+		return readAlphanumericData(reader, charCount)
+	case Encode_Byte:
+		return readByteData(reader, charCount)
+	case Encode_Kanji:
+		return readKanjiData(reader, charCount)
+	}
+	return nil, fmt.Errorf("invalid encoding mode")
+}
+
+func readNumericData(reader *bitreader.BitReader, charCount int) ([]byte, error) {
+	out := make([]byte, 0, charCount)
+
+	for remaining := charCount; remaining > 0; {
+		digits, size := 3, uint8(10)
+		if remaining == 2 {
+			digits, size = 2, 7
+		} else if remaining == 1 {
+			digits, size = 1, 4
+		}
+
+		if reader.Remaining() < int(size) {
+			return nil, fmt.Errorf("truncated numeric segment")
+		}
+		val := reader.ReadUInt(size)
+
+		formatted := fmt.Sprintf("%0*d", digits, val)
+		if len(formatted) != digits {
+			return nil, fmt.Errorf("invalid numeric group %d", val)
+		}
+		out = append(out, formatted...)
+		remaining -= digits
+	}
+	return out, nil
+}
+
+func readAlphanumericData(reader *bitreader.BitReader, charCount int) ([]byte, error) {
+	out := make([]byte, 0, charCount)
+
+	for remaining := charCount; remaining > 0; {
+		if remaining == 1 {
+			if reader.Remaining() < 6 {
+				return nil, fmt.Errorf("truncated alphanumeric segment")
+			}
+			val := reader.ReadUInt(6)
+			if val >= 45 {
+				return nil, fmt.Errorf("invalid alphanumeric value %d", val)
+			}
+			out = append(out, alphanumericChars[val])
+			break
+		}
+
+		if reader.Remaining() < 11 {
+			return nil, fmt.Errorf("truncated alphanumeric segment")
+		}
+		val := reader.ReadUInt(11)
+		if val >= 45*45 {
+			return nil, fmt.Errorf("invalid alphanumeric value %d", val)
+		}
+		out = append(out, alphanumericChars[val/45], alphanumericChars[val%45])
+		remaining -= 2
+	}
+	return out, nil
+}
+
+func readByteData(reader *bitreader.BitReader, charCount int) ([]byte, error) {
+	if reader.Remaining() < charCount*8 {
+		return nil, fmt.Errorf("truncated byte segment")
+	}
+
+	out := make([]byte, charCount)
+	for i := range out {
+		out[i] = byte(reader.ReadUInt(8))
+	}
+	return out, nil
+}
+
+func readKanjiData(reader *bitreader.BitReader, charCount int) ([]byte, error) {
+	if reader.Remaining() < charCount*13 {
+		return nil, fmt.Errorf("truncated kanji segment")
+	}
+
+	sjis := make([]byte, 0, charCount*2)
+	for range charCount {
+		val := uint16(reader.ReadUInt(13))
+
+		// Undo the packing done in writeKanjiString
+		code := (val/0xC0)<<8 | val%0xC0
+		if code < 0x1F00 {
+			code += 0x8140
+		} else {
+			code += 0xC140
+		}
+		sjis = append(sjis, byte(code>>8), byte(code))
+	}
+
+	return japanese.ShiftJIS.NewDecoder().Bytes(sjis)
+}
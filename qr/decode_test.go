@@ -0,0 +1,308 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Nearest neighbour rotation around the center, background stays white
+func rotateImage(src image.Image, degrees float64) *image.RGBA {
+	bounds := src.Bounds()
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+
+	dw := int(math.Abs(w*cos)+math.Abs(h*sin)) + 2
+	dh := int(math.Abs(w*sin)+math.Abs(h*cos)) + 2
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+
+	for y := range dh {
+		for x := range dw {
+			dx, dy := float64(x)-float64(dw)/2, float64(y)-float64(dh)/2
+			sx, sy := cos*dx+sin*dy+w/2, -sin*dx+cos*dy+h/2
+			if sx >= 0 && sy >= 0 && sx < w && sy < h {
+				dst.Set(x, y, src.At(bounds.Min.X+int(sx), bounds.Min.Y+int(sy)))
+			}
+		}
+	}
+	return dst
+}
+
+func TestCorrectBlock(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236}
+	ecInfo := getEcInfo(1, EC_Quartile)
+	original := append(append([]byte{}, data...), generateErrorCorrectionCodeWords(data, ecInfo)...)
+
+	rng := rand.New(rand.NewSource(1))
+	for numErrors := 0; numErrors <= 6; numErrors++ {
+		block := append([]byte{}, original...)
+		for _, pos := range rng.Perm(len(block))[:numErrors] {
+			block[pos] ^= byte(rng.Intn(255) + 1)
+		}
+
+		n, err := correctBlock(block, ecInfo.ECCodewordsPerBlock, 6, nil)
+		if err != nil {
+			t.Fatalf("%d errors: %v", numErrors, err)
+		}
+		if n != numErrors {
+			t.Errorf("%d errors: corrected %d", numErrors, n)
+		}
+		if string(block) != string(original) {
+			t.Errorf("%d errors: block not restored", numErrors)
+		}
+	}
+}
+
+func TestCorrectBlockErasures(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236}
+	ecInfo := getEcInfo(1, EC_Quartile)
+	original := append(append([]byte{}, data...), generateErrorCorrectionCodeWords(data, ecInfo)...)
+
+	// 1-Q corrects 6 errors, or 12 erasures, or anything in between
+	rng := rand.New(rand.NewSource(1))
+	for _, c := range []struct{ errors, erasures int }{{0, 12}, {2, 8}, {5, 2}, {0, 3}, {6, 0}} {
+		block := append([]byte{}, original...)
+		perm := rng.Perm(len(block))
+		for _, pos := range perm[:c.errors+c.erasures] {
+			block[pos] ^= byte(rng.Intn(255) + 1)
+		}
+
+		n, err := correctBlock(block, ecInfo.ECCodewordsPerBlock, 6, perm[c.errors:c.errors+c.erasures])
+		if err != nil {
+			t.Fatalf("%d errors, %d erasures: %v", c.errors, c.erasures, err)
+		}
+		if n != c.errors+c.erasures || string(block) != string(original) {
+			t.Errorf("%d errors, %d erasures: corrected %d, restored %t", c.errors, c.erasures, n, string(block) == string(original))
+		}
+	}
+
+	// Beyond the capacity
+	block := append([]byte{}, original...)
+	perm := rng.Perm(len(block))
+	for _, pos := range perm[:10] {
+		block[pos] ^= 0x55
+	}
+	if _, err := correctBlock(block, ecInfo.ECCodewordsPerBlock, 6, perm[3:10]); err == nil {
+		t.Error("3 errors + 7 erasures is beyond the capacity")
+	}
+}
+
+func TestDecodeGridRoundTrip(t *testing.T) {
+	inputs := []string{"01234567890123", "HELLO WORLD", "Hello, wörld!", "漢字テスト"}
+	rng := rand.New(rand.NewSource(1))
+
+	for _, version := range []int{1, 2, 7, 10, 27, 40} {
+		for ecLevel := EC_Low; ecLevel <= EC_High; ecLevel++ {
+			for _, input := range inputs {
+				mode := determineBestEncodingMode(input)
+				charCount, _ := getCharCount(mode, input)
+				if getMaxCharCapacity(Version(version), ecLevel, mode) < charCount {
+					continue
+				}
+
+				qrCode := GenerateQRCode(input, ecLevel, version, false)
+				grid := qrCode.BitMatrix()
+
+				// Damage as many codewords of the first block as it can take
+				ecInfo := getEcInfo(qrCode.Version, ecLevel)
+				positions := newTemplate(qrCode.Version).dataPositions()
+				damaged := 0
+				for i, pos := range interleaveOrder(ecInfo) {
+					if pos[0] != 0 || damaged == correctionCapacity(qrCode.Version, ecLevel) {
+						continue
+					}
+					p := positions[i*8+rng.Intn(8)]
+					grid[p[0]][p[1]] = !grid[p[0]][p[1]]
+					damaged++
+				}
+
+				result, err := DecodeGrid(grid)
+				if err != nil {
+					t.Errorf("v%d %s %q: %v", version, getErrorCorrectionString(ecLevel), input, err)
+					continue
+				}
+				if result.Text() != input {
+					t.Errorf("v%d %s: got %q, want %q", version, getErrorCorrectionString(ecLevel), result.Text(), input)
+				}
+				if result.ErrorsCorrected[0] != damaged {
+					t.Errorf("v%d %s: corrected %v, want %d in block 0", version, getErrorCorrectionString(ecLevel), result.ErrorsCorrected, damaged)
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeImageRotated(t *testing.T) {
+	input := "Hello pallet label 123"
+	for _, version := range []int{2, 7, 20, 40} {
+		for _, degrees := range []float64{0, 13, 45, 90, 200} {
+			qrCode := GenerateQRCode(input, EC_Low, version, false)
+			img := rotateImage(qrCode.GenerateImage(4), degrees)
+
+			results, err := DecodeImage(img)
+			if err != nil {
+				t.Errorf("v%d %.0f°: %v", version, degrees, err)
+				continue
+			}
+			if len(results) != 1 || results[0].Text() != input {
+				t.Errorf("v%d %.0f°: got %d results", version, degrees, len(results))
+			}
+		}
+	}
+}
+
+func TestDecodeImageMultiple(t *testing.T) {
+	canvas := image.NewRGBA(image.Rect(0, 0, 1400, 1100))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+
+	want := map[string]bool{}
+	for i := range 12 {
+		input := fmt.Sprintf("LABEL-%04d", i*37)
+		if i%3 == 0 {
+			input += "-with-a-longer-payload-for-a-bigger-version"
+		}
+		want[input] = true
+
+		qrCode := GenerateQRCode(input, ErrorCorrectionLevel(i%4), 0, false)
+		img := rotateImage(qrCode.GenerateImage(4), float64(i*29))
+		offset := image.Pt((i%4)*340, (i/4)*360)
+		draw.Draw(canvas, img.Bounds().Add(offset), img, image.Point{}, draw.Over)
+	}
+
+	results, err := DecodeImage(canvas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(results))
+	}
+
+	for _, r := range results {
+		if !want[r.Text()] {
+			t.Errorf("unexpected or duplicate result %q", r.Text())
+		}
+		delete(want, r.Text())
+
+		center := quadCenter(r.Corners)
+		if !insideQuad(r.Corners, center) || distance(r.Corners[0], r.Corners[2]) < 50 {
+			t.Errorf("%q: implausible corners %v", r.Text(), r.Corners)
+		}
+	}
+}
+
+// Horizontal flip, like looking at the symbol through glass
+func mirrorImage(src *image.RGBA) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			dst.Set(bounds.Max.X-1-(x-bounds.Min.X), y, src.At(x, y))
+		}
+	}
+	return dst
+}
+
+func TestDecodeImageVariants(t *testing.T) {
+	input := "https://example.com/brand"
+	qrCode := GenerateQRCode(input, EC_Medium, 0, false)
+
+	cases := []struct {
+		name     string
+		img      *image.RGBA
+		inverted bool
+		mirrored bool
+	}{
+		{"normal", qrCode.GenerateImage(4), false, false},
+		{"inverted", qrCode.GenerateImageWithOptions(ImageOptions{Scale: 4, Invert: true}), true, false},
+		{"mirrored", mirrorImage(qrCode.GenerateImage(4)), false, true},
+		{"inverted+mirrored", rotateImage(mirrorImage(qrCode.GenerateImageWithOptions(ImageOptions{Scale: 4, Invert: true})), 30), true, true},
+	}
+
+	for _, c := range cases {
+		results, err := DecodeImage(c.img)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if len(results) != 1 || results[0].Text() != input {
+			t.Errorf("%s: got %d results", c.name, len(results))
+			continue
+		}
+		if results[0].Inverted != c.inverted || results[0].Mirrored != c.mirrored {
+			t.Errorf("%s: got inverted=%v mirrored=%v", c.name, results[0].Inverted, results[0].Mirrored)
+		}
+	}
+}
+
+func TestDecodeImageNotFound(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	if _, err := DecodeImage(img); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDecodeErasures(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/erasures", EC_Medium, 4, false)
+
+	// A "logo" in the middle, too big to correct as unknown errors
+	grid := qrCode.BitMatrix()
+	mask := make([][]bool, qrCode.size)
+	for x := range mask {
+		mask[x] = make([]bool, qrCode.size)
+	}
+	start := (qrCode.size - 13) / 2
+	for x := start; x < start+13; x++ {
+		for y := start; y < start+13; y++ {
+			grid[x][y] = x%2 == 0
+			mask[x][y] = true
+		}
+	}
+
+	if _, err := DecodeGrid(grid); err == nil {
+		t.Fatal("expected the damage to be too much without erasures")
+	}
+
+	result, err := DecodeGridWithOptions(grid, DecodeOptions{Erasures: mask})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Text() != "https://example.com/erasures" {
+		t.Errorf("decoded %q", result.Text())
+	}
+	if len(result.Erasures) != 2 || result.Erasures[0] == 0 {
+		t.Errorf("expected erasures in both blocks, got %v", result.Erasures)
+	}
+
+	// A mask that doesn't match the grid is a mistake, not "no erasures"
+	if _, err := DecodeGridWithOptions(grid, DecodeOptions{Erasures: mask[1:]}); err == nil {
+		t.Error("expected an error for a mask with too few columns")
+	}
+	if _, err := DecodeGridWithOptions(grid, DecodeOptions{Erasures: [][]bool{}}); err == nil {
+		t.Error("expected an error for an empty mask")
+	}
+
+	// Same through an image, the mask is in module coordinates
+	damaged := New(qrCode.Version, qrCode.EcLevel)
+	for x := range grid {
+		for y := range grid {
+			value := ValueWhite
+			if grid[x][y] {
+				value = ValueBlack
+			}
+			damaged.setModule(x, y, value, false)
+		}
+	}
+	results, err := DecodeImageWithOptions(damaged.GenerateImage(4), DecodeOptions{Erasures: mask})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Text() != "https://example.com/erasures" {
+		t.Errorf("decoded %q from image", results[0].Text())
+	}
+}
@@ -0,0 +1,752 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// Luminance of an image, 0 = black, 255 = white.
+// Transparent pixels are treated as if they were on white paper.
+type lumImage struct {
+	w, h int
+	pix  []uint8
+}
+
+func toLuminance(img image.Image) *lumImage {
+	bounds := img.Bounds()
+	lum := &lumImage{
+		w:   bounds.Dx(),
+		h:   bounds.Dy(),
+		pix: make([]uint8, bounds.Dx()*bounds.Dy()),
+	}
+
+	switch src := img.(type) {
+	case *image.Gray:
+		for y := range lum.h {
+			start := src.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			copy(lum.pix[y*lum.w:(y+1)*lum.w], src.Pix[start:start+lum.w])
+		}
+	default:
+		for y := range lum.h {
+			for x := range lum.w {
+				r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				l := (19595*r+38470*g+7471*b+1<<15)>>16 + (0xffff - a)
+				lum.pix[y*lum.w+x] = uint8(min(l, 0xffff) >> 8)
+			}
+		}
+	}
+
+	return lum
+}
+
+// Binarized image, true = dark
+type bitImage struct {
+	w, h int
+	dark []bool
+}
+
+// Out of bounds is considered light (quiet zone)
+func (b *bitImage) at(x, y int) bool {
+	if x < 0 || y < 0 || x >= b.w || y >= b.h {
+		return false
+	}
+	return b.dark[y*b.w+x]
+}
+
+func (b *bitImage) inBounds(x, y int) bool {
+	return x >= 0 && y >= 0 && x < b.w && y < b.h
+}
+
+const binarizeBlockSize = 8
+
+// Local thresholding: each 8x8 block gets a threshold that is the average of
+// the blocks around it, so uneven lighting doesn't wash out parts of a symbol.
+// Blocks with hardly any contrast borrow their neighbour's average instead.
+func binarize(lum *lumImage) *bitImage {
+	bw := (lum.w + binarizeBlockSize - 1) / binarizeBlockSize
+	bh := (lum.h + binarizeBlockSize - 1) / binarizeBlockSize
+
+	averages := make([]int, bw*bh)
+	for by := range bh {
+		for bx := range bw {
+			lo, hi, sum, n := 255, 0, 0, 0
+			for y := by * binarizeBlockSize; y < min((by+1)*binarizeBlockSize, lum.h); y++ {
+				for x := bx * binarizeBlockSize; x < min((bx+1)*binarizeBlockSize, lum.w); x++ {
+					v := int(lum.pix[y*lum.w+x])
+					lo = min(lo, v)
+					hi = max(hi, v)
+					sum += v
+					n++
+				}
+			}
+
+			avg := sum / n
+			if hi-lo <= 24 {
+				// Flat block. Assume it's light unless the neighbours say otherwise
+				avg = lo / 2
+				if by > 0 && bx > 0 {
+					neighbours := (averages[(by-1)*bw+bx] + 2*averages[by*bw+bx-1] + averages[(by-1)*bw+bx-1]) / 4
+					if lo < neighbours {
+						avg = neighbours
+					}
+				}
+			}
+			averages[by*bw+bx] = avg
+		}
+	}
+
+	bits := &bitImage{w: lum.w, h: lum.h, dark: make([]bool, lum.w*lum.h)}
+	for by := range bh {
+		for bx := range bw {
+			sum, n := 0, 0
+			for ny := max(by-2, 0); ny <= min(by+2, bh-1); ny++ {
+				for nx := max(bx-2, 0); nx <= min(bx+2, bw-1); nx++ {
+					sum += averages[ny*bw+nx]
+					n++
+				}
+			}
+			threshold := sum / n
+
+			for y := by * binarizeBlockSize; y < min((by+1)*binarizeBlockSize, lum.h); y++ {
+				for x := bx * binarizeBlockSize; x < min((bx+1)*binarizeBlockSize, lum.w); x++ {
+					bits.dark[y*lum.w+x] = int(lum.pix[y*lum.w+x]) <= threshold
+				}
+			}
+		}
+	}
+
+	return bits
+}
+
+type finderCandidate struct {
+	center     Point
+	moduleSize float64
+	count      int // How many scan lines confirmed this finder
+}
+
+// Scans every row for the 1:1:3:1:1 dark/light/dark/light/dark ratio of a
+// finder pattern, then confirms the hit vertically and horizontally.
+func (b *bitImage) findFinderCandidates() []*finderCandidate {
+	var candidates []*finderCandidate
+	runs := make([]int, 0, 64)
+	starts := make([]int, 0, 64)
+
+	for y := range b.h {
+		runs = runs[:0]
+		starts = starts[:0]
+		for x := 0; x < b.w; {
+			start := x
+			dark := b.at(x, y)
+			for x < b.w && b.at(x, y) == dark {
+				x++
+			}
+			runs = append(runs, x-start)
+			starts = append(starts, start)
+		}
+
+		firstDark := b.at(0, y)
+		for i := 0; i+4 < len(runs); i++ {
+			isDark := (i%2 == 0) == firstDark
+			if !isDark || !foundPatternCross(runs[i:i+5]) {
+				continue
+			}
+
+			centerX := float64(starts[i+2]) + float64(runs[i+2])/2
+			total := runs[i] + runs[i+1] + runs[i+2] + runs[i+3] + runs[i+4]
+			candidates = b.handlePossibleCenter(candidates, centerX, y, total)
+		}
+	}
+
+	confirmed := candidates[:0]
+	for _, c := range candidates {
+		if c.count >= 2 {
+			confirmed = append(confirmed, c)
+		}
+	}
+	return confirmed
+}
+
+func (b *bitImage) handlePossibleCenter(candidates []*finderCandidate, centerX float64, y, total int) []*finderCandidate {
+	x := int(centerX)
+	vertical, offY, ok := b.crossCheck(x, y, 0, 1, total)
+	if !ok || !similarTotal(vertical, total) {
+		return candidates
+	}
+
+	centerY := float64(y) + offY + 0.5
+	horizontal, offX, ok := b.crossCheck(x, int(centerY), 1, 0, total)
+	if !ok || !similarTotal(horizontal, total) {
+		return candidates
+	}
+
+	center := Point{float64(x) + offX + 0.5, centerY}
+	moduleSize := float64(sum(horizontal[:])+sum(vertical[:])) / 14
+
+	for _, c := range candidates {
+		if c.aboutEquals(center, moduleSize) {
+			n := float64(c.count)
+			c.center.X = (c.center.X*n + center.X) / (n + 1)
+			c.center.Y = (c.center.Y*n + center.Y) / (n + 1)
+			c.moduleSize = (c.moduleSize*n + moduleSize) / (n + 1)
+			c.count++
+			return candidates
+		}
+	}
+
+	return append(candidates, &finderCandidate{center: center, moduleSize: moduleSize, count: 1})
+}
+
+func (c *finderCandidate) aboutEquals(center Point, moduleSize float64) bool {
+	if math.Abs(center.X-c.center.X) > moduleSize || math.Abs(center.Y-c.center.Y) > moduleSize {
+		return false
+	}
+	diff := math.Abs(moduleSize - c.moduleSize)
+	return diff <= 1 || diff <= c.moduleSize
+}
+
+// Measures the 5 runs of a finder through (cx, cy) along (dx, dy).
+// Also returns how far the center of the middle run is from (cx, cy).
+func (b *bitImage) crossCheck(cx, cy, dx, dy, limit int) ([5]int, float64, bool) {
+	var counts [5]int
+	if !b.at(cx, cy) {
+		return counts, 0, false
+	}
+
+	walk := func(x, y, sign int, dark bool, count *int) (int, int) {
+		for b.inBounds(x, y) && b.at(x, y) == dark && *count <= limit {
+			*count++
+			x += sign * dx
+			y += sign * dy
+		}
+		return x, y
+	}
+
+	back, fwd := 0, 0
+	x, y := walk(cx-dx, cy-dy, -1, true, &back)
+	x, y = walk(x, y, -1, false, &counts[1])
+	walk(x, y, -1, true, &counts[0])
+
+	x, y = walk(cx+dx, cy+dy, 1, true, &fwd)
+	x, y = walk(x, y, 1, false, &counts[3])
+	walk(x, y, 1, true, &counts[4])
+
+	counts[2] = back + fwd + 1
+	if !foundPatternCross(counts[:]) {
+		return counts, 0, false
+	}
+	return counts, float64(fwd-back) / 2, true
+}
+
+// Checks the run lengths for the finder's 1:1:3:1:1 ratio
+func foundPatternCross(counts []int) bool {
+	total := 0
+	for _, c := range counts {
+		if c == 0 {
+			return false
+		}
+		total += c
+	}
+	if total < 7 {
+		return false
+	}
+
+	moduleSize := float64(total) / 7
+	maxVariance := moduleSize / 2
+
+	return math.Abs(moduleSize-float64(counts[0])) < maxVariance &&
+		math.Abs(moduleSize-float64(counts[1])) < maxVariance &&
+		math.Abs(3*moduleSize-float64(counts[2])) < 3*maxVariance &&
+		math.Abs(moduleSize-float64(counts[3])) < maxVariance &&
+		math.Abs(moduleSize-float64(counts[4])) < maxVariance
+}
+
+// The cross checks must roughly agree with the row that found the pattern
+func similarTotal(counts [5]int, total int) bool {
+	return 5*abs(sum(counts[:])-total) < 2*total
+}
+
+// Three finders that plausibly belong to the same symbol
+type symbolLocation struct {
+	topLeft, topRight, bottomLeft *finderCandidate
+	score                         float64 // Lower is better
+}
+
+const maxFinderCandidates = 100
+
+// Tries every combination of three finders and keeps the ones that form
+// (roughly) a right isosceles triangle with the right angle at the top-left.
+// With several symbols in one image there are a lot of "wrong" triples, those
+// are weeded out by module size and geometry. Triangles with other finders
+// inside them are most likely mixing symbols, so they are tried last.
+// (Data modules can look like a finder too, so they're not rejected outright.)
+func groupFinders(candidates []*finderCandidate) []symbolLocation {
+	if len(candidates) > maxFinderCandidates {
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].count > candidates[j].count })
+		candidates = candidates[:maxFinderCandidates]
+	}
+
+	var locations []symbolLocation
+	n := len(candidates)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			for k := j + 1; k < n; k++ {
+				loc, ok := makeLocation(candidates[i], candidates[j], candidates[k])
+				if !ok {
+					continue
+				}
+				loc.score += float64(loc.countOtherFinders(candidates))
+				locations = append(locations, loc)
+			}
+		}
+	}
+
+	sort.SliceStable(locations, func(i, j int) bool { return locations[i].score < locations[j].score })
+	return locations
+}
+
+func makeLocation(a, b, c *finderCandidate) (symbolLocation, bool) {
+	minSize := min(a.moduleSize, b.moduleSize, c.moduleSize)
+	maxSize := max(a.moduleSize, b.moduleSize, c.moduleSize)
+	if maxSize > minSize*1.5 {
+		return symbolLocation{}, false
+	}
+
+	// The corner finder is the one opposite of the longest side
+	ab, ac, bc := distance(a.center, b.center), distance(a.center, c.center), distance(b.center, c.center)
+	switch {
+	case bc >= ab && bc >= ac:
+		// a is the corner
+	case ac >= ab && ac >= bc:
+		a, b = b, a
+		ac, bc = bc, ac
+	default:
+		a, c = c, a
+		ab, bc = bc, ab
+	}
+
+	legRatio := math.Abs(ab-ac) / max(ab, ac)
+	if legRatio > 0.25 {
+		return symbolLocation{}, false
+	}
+
+	expectedHyp := math.Sqrt(ab*ab + ac*ac)
+	angleErr := math.Abs(bc-expectedHyp) / expectedHyp
+	if angleErr > 0.1 {
+		return symbolLocation{}, false
+	}
+
+	moduleSize := (a.moduleSize + b.moduleSize + c.moduleSize) / 3
+	dimension := (ab+ac)/2/moduleSize + 7
+	if dimension < 12 || dimension > 200 {
+		return symbolLocation{}, false
+	}
+
+	// Make sure b is top-right and c is bottom-left (clockwise in image coordinates)
+	if cross(a.center, b.center, c.center) < 0 {
+		b, c = c, b
+	}
+
+	return symbolLocation{
+		topLeft:    a,
+		topRight:   b,
+		bottomLeft: c,
+		score:      legRatio + angleErr + (maxSize/minSize - 1),
+	}, true
+}
+
+// Counts the finders (other than the three) inside the parallelogram spanned by the location
+func (loc symbolLocation) countOtherFinders(candidates []*finderCandidate) int {
+	quad := [4]Point{
+		loc.topLeft.center,
+		loc.topRight.center,
+		{loc.topRight.center.X + loc.bottomLeft.center.X - loc.topLeft.center.X, loc.topRight.center.Y + loc.bottomLeft.center.Y - loc.topLeft.center.Y},
+		loc.bottomLeft.center,
+	}
+
+	count := 0
+	for _, c := range candidates {
+		if c == loc.topLeft || c == loc.topRight || c == loc.bottomLeft {
+			continue
+		}
+		if insideQuad(quad, c.center) {
+			count++
+		}
+	}
+	return count
+}
+
+// Estimates the module size by measuring the finders along the line that
+// connects them. A finder is 7 modules wide through its center, whatever the rotation.
+func (b *bitImage) moduleSizeAlong(from, to Point) float64 {
+	size := b.finderWidthAlong(from, to, 1) + b.finderWidthAlong(from, to, -1)
+	return size / 7
+}
+
+// Distance from the center of a finder to its outer edge, heading towards (sign 1)
+// or away from (sign -1) another point.
+func (b *bitImage) finderWidthAlong(from, to Point, sign float64) float64 {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return math.NaN()
+	}
+	dx, dy = sign*dx/length, sign*dy/length
+
+	// Dark center -> light ring -> dark ring -> light separator
+	state := 0
+	for step := 0.0; step < length; step += 0.5 {
+		x, y := from.X+dx*step, from.Y+dy*step
+		if !b.inBounds(int(x), int(y)) {
+			return math.NaN()
+		}
+
+		dark := b.at(int(x), int(y))
+		if (state%2 == 0) != dark {
+			state++
+			if state == 3 {
+				return step
+			}
+		}
+	}
+	return math.NaN()
+}
+
+func (b *bitImage) estimateModuleSize(loc symbolLocation) float64 {
+	tl, tr, bl := loc.topLeft.center, loc.topRight.center, loc.bottomLeft.center
+
+	total, n := 0.0, 0
+	for _, size := range []float64{b.moduleSizeAlong(tl, tr), b.moduleSizeAlong(tr, tl), b.moduleSizeAlong(tl, bl), b.moduleSizeAlong(bl, tl)} {
+		if !math.IsNaN(size) && size > 0 {
+			total += size
+			n++
+		}
+	}
+
+	if n == 0 {
+		return (loc.topLeft.moduleSize + loc.topRight.moduleSize + loc.bottomLeft.moduleSize) / 3
+	}
+	return total / float64(n)
+}
+
+// Symbol size in modules, snapped to a valid 4V+17
+func estimateDimension(loc symbolLocation, moduleSize float64) int {
+	tltr := int(math.Round(distance(loc.topLeft.center, loc.topRight.center) / moduleSize))
+	tlbl := int(math.Round(distance(loc.topLeft.center, loc.bottomLeft.center) / moduleSize))
+	dimension := (tltr+tlbl)/2 + 7
+
+	switch dimension % 4 {
+	case 0:
+		dimension++
+	case 2:
+		dimension--
+	case 3:
+		dimension += 2
+	}
+	return dimension
+}
+
+// Builds the module space -> image space transform. Finder centers sit at
+// module 3.5 from the edges, the bottom-right alignment pattern (version 2+)
+// at 6.5. Without an alignment pattern the symbol is assumed to be a parallelogram.
+func (b *bitImage) symbolTransform(loc symbolLocation, dimension int, moduleSize float64) perspective {
+	tl, tr, bl := loc.topLeft.center, loc.topRight.center, loc.bottomLeft.center
+	dim := float64(dimension)
+
+	src := [4]Point{{3.5, 3.5}, {dim - 3.5, 3.5}, {dim - 3.5, dim - 3.5}, {3.5, dim - 3.5}}
+	dst := [4]Point{tl, tr, {tr.X + bl.X - tl.X, tr.Y + bl.Y - tl.Y}, bl}
+
+	if dimension > 21 {
+		between := dim - 7
+		ex := Point{(tr.X - tl.X) / between, (tr.Y - tl.Y) / between}
+		ey := Point{(bl.X - tl.X) / between, (bl.Y - tl.Y) / between}
+		estimate := Point{tl.X + (dim-10)*(ex.X+ey.X), tl.Y + (dim-10)*(ex.Y+ey.Y)}
+
+		if p, ok := b.findAlignmentPattern(estimate, ex, ey, moduleSize); ok {
+			src[2] = Point{dim - 6.5, dim - 6.5}
+			dst[2] = p
+		}
+	}
+
+	return quadToQuad(src, dst)
+}
+
+// Searches around the estimated position for the 5x5 alignment pattern by
+// sampling the template with the symbol's own module vectors (so rotation is fine).
+// Starts close by and widens the search if nothing is found.
+func (b *bitImage) findAlignmentPattern(estimate, ex, ey Point, moduleSize float64) (Point, bool) {
+	step := max(1, moduleSize/4)
+
+	for _, radiusModules := range []float64{3, 8} {
+		radius := radiusModules * moduleSize
+		bestScore := 0
+		bestDist := math.Inf(1)
+		var best Point
+
+		for dy := -radius; dy <= radius; dy += step {
+			for dx := -radius; dx <= radius; dx += step {
+				center := Point{estimate.X + dx, estimate.Y + dy}
+				score := 0
+				for i := range 5 {
+					for j := range 5 {
+						x := center.X + float64(i-2)*ex.X + float64(j-2)*ey.X
+						y := center.Y + float64(i-2)*ex.Y + float64(j-2)*ey.Y
+						if b.at(int(math.Floor(x)), int(math.Floor(y))) == alignmentPattern[i][j] {
+							score++
+						}
+					}
+				}
+
+				dist := math.Hypot(dx, dy)
+				if score > bestScore || (score == bestScore && dist < bestDist) {
+					bestScore = score
+					bestDist = dist
+					best = center
+				}
+			}
+		}
+
+		if bestScore >= 23 {
+			return best, true
+		}
+	}
+
+	return Point{}, false
+}
+
+// Samples the center of every module through the transform
+func (b *bitImage) sampleGrid(t perspective, dimension int) ([][]bool, bool) {
+	grid := make([][]bool, dimension)
+	for x := range dimension {
+		grid[x] = make([]bool, dimension)
+		for y := range dimension {
+			p := t.transform(float64(x)+0.5, float64(y)+0.5)
+			ix, iy := int(math.Floor(p.X)), int(math.Floor(p.Y))
+			if ix < -1 || iy < -1 || ix > b.w || iy > b.h {
+				return nil, false
+			}
+			grid[x][y] = b.at(ix, iy)
+		}
+	}
+	return grid, true
+}
+
+// Samples and decodes the symbol at the given location. The estimated
+// dimension can be off by a version step or two, so the neighbours are tried
+// too, and for version 7+ the version info is used to correct the guess.
+func (b *bitImage) decodeLocation(loc symbolLocation, opts DecodeOptions) (*DecodeResult, error) {
+	moduleSize := b.estimateModuleSize(loc)
+	estimate := estimateDimension(loc, moduleSize)
+
+	estimate = min(max(estimate, 21), 177)
+
+	tried := map[int]bool{}
+	dimensions := []int{estimate, estimate + 4, estimate - 4, estimate + 8, estimate - 8}
+	err := fmt.Errorf("%w: symbol out of bounds", ErrUndecodable)
+
+	for i := 0; i < len(dimensions); i++ {
+		dimension := dimensions[i]
+		if tried[dimension] || dimension < 21 || dimension > 177 {
+			continue
+		}
+		tried[dimension] = true
+
+		t := b.symbolTransform(loc, dimension, moduleSize)
+		grid, ok := b.sampleGrid(t, dimension)
+		if !ok {
+			continue
+		}
+
+		// The mask is only for symbols of its size, the others decode without it
+		gridOpts := opts
+		if len(opts.Erasures) != dimension {
+			gridOpts.Erasures = nil
+		}
+
+		var result *DecodeResult
+		result, err = DecodeGridWithOptions(grid, gridOpts)
+		if err == nil {
+			dim := float64(dimension)
+			result.transform = t
+			result.Corners = [4]Point{t.transform(0, 0), t.transform(dim, 0), t.transform(dim, dim), t.transform(0, dim)}
+			if result.Mirrored {
+				// The symbol's own top-right is where the sampled grid has its bottom-left
+				result.Corners[1], result.Corners[3] = result.Corners[3], result.Corners[1]
+			}
+			return result, nil
+		}
+
+		if dimension >= 45 {
+			if v, ok := newTemplate(Version((dimension - 17) / 4)).readVersionInfo(grid); ok {
+				dimensions = append(dimensions, int(v)*4+17)
+			}
+		}
+	}
+
+	return nil, err
+}
+
+// DecodeImage finds every QR code in img and decodes each one independently.
+// Results are ordered top to bottom, left to right. Symbols that couldn't be
+// decoded are left out; if none could be decoded the error tells whether
+// nothing was found (ErrNotFound) or decoding failed (ErrUndecodable).
+//
+// When nothing decodes, the image is retried with inverted luminance for
+// light-on-dark symbols. Mirrored symbols are handled by DecodeGrid.
+func DecodeImage(img image.Image) ([]*DecodeResult, error) {
+	return DecodeImageWithOptions(img, DecodeOptions{})
+}
+
+func DecodeImageWithOptions(img image.Image, opts DecodeOptions) ([]*DecodeResult, error) {
+	return decodeLuminance(toLuminance(img), opts)
+}
+
+func decodeLuminance(lum *lumImage, opts DecodeOptions) ([]*DecodeResult, error) {
+	bits := binarize(lum)
+	results, err := bits.decodeAll(opts)
+	if err == nil {
+		return results, nil
+	}
+
+	bits.invert()
+	inverted, invErr := bits.decodeAll(opts)
+	if invErr != nil {
+		// ErrUndecodable is more useful than ErrNotFound from the inverted pass
+		if errors.Is(invErr, ErrNotFound) {
+			return nil, err
+		}
+		if errors.Is(err, ErrNotFound) {
+			return nil, invErr
+		}
+		return nil, err
+	}
+
+	for _, r := range inverted {
+		r.Inverted = !r.Inverted
+	}
+	return inverted, nil
+}
+
+func (b *bitImage) invert() {
+	for i := range b.dark {
+		b.dark[i] = !b.dark[i]
+	}
+}
+
+// Maximum amount of finder triples that are sampled before giving up
+const maxDecodeAttempts = 500
+
+func (b *bitImage) decodeAll(opts DecodeOptions) ([]*DecodeResult, error) {
+	locations := groupFinders(b.findFinderCandidates())
+	if len(locations) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var results []*DecodeResult
+	var lastErr error
+	used := map[*finderCandidate]bool{}
+
+	for i, loc := range locations {
+		if i >= maxDecodeAttempts {
+			break
+		}
+		if used[loc.topLeft] || used[loc.topRight] || used[loc.bottomLeft] {
+			continue
+		}
+
+		// Already decoded through a different triple (duplicate)
+		centroid := Point{
+			(loc.topRight.center.X + loc.bottomLeft.center.X) / 2,
+			(loc.topRight.center.Y + loc.bottomLeft.center.Y) / 2,
+		}
+		if isDuplicate(results, centroid) {
+			continue
+		}
+
+		result, err := b.decodeLocation(loc, opts)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		used[loc.topLeft] = true
+		used[loc.topRight] = true
+		used[loc.bottomLeft] = true
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		return nil, lastErr
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		ci, cj := quadCenter(results[i].Corners), quadCenter(results[j].Corners)
+		if math.Abs(ci.Y-cj.Y) > quadHeight(results[i].Corners)/2 {
+			return ci.Y < cj.Y
+		}
+		return ci.X < cj.X
+	})
+	return results, nil
+}
+
+func isDuplicate(results []*DecodeResult, p Point) bool {
+	for _, r := range results {
+		if insideQuad(r.Corners, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ===== Geometry helpers =====
+
+func distance(a, b Point) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
+
+// z component of (b - a) x (c - a)
+func cross(a, b, c Point) float64 {
+	return (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+}
+
+// Works for convex quads in either winding order
+func insideQuad(quad [4]Point, p Point) bool {
+	pos, neg := false, false
+	for i := range 4 {
+		c := cross(quad[i], quad[(i+1)%4], p)
+		if c > 0 {
+			pos = true
+		} else if c < 0 {
+			neg = true
+		}
+	}
+	return !(pos && neg)
+}
+
+func quadCenter(quad [4]Point) Point {
+	return Point{
+		(quad[0].X + quad[1].X + quad[2].X + quad[3].X) / 4,
+		(quad[0].Y + quad[1].Y + quad[2].Y + quad[3].Y) / 4,
+	}
+}
+
+func quadHeight(quad [4]Point) float64 {
+	return (distance(quad[0], quad[3]) + distance(quad[1], quad[2])) / 2
+}
+
+func sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,197 @@
+package qr
+
+import (
+	"aboutblank/qr-code/gf256"
+	"fmt"
+)
+
+// Number of codewords per block that are reserved for misdecode protection
+// (ISO/IEC 18004 Table 9, the "p" column). Only the smallest symbols have any.
+func misdecodeProtection(version Version, ecLevel ErrorCorrectionLevel) int {
+	switch {
+	case version == 1 && ecLevel == EC_Low:
+		return 3
+	case version == 1 && ecLevel == EC_Medium:
+		return 2
+	case version == 1:
+		return 1
+	case version == 2 && ecLevel == EC_Low:
+		return 2
+	case version == 3 && ecLevel == EC_Low:
+		return 1
+	}
+	return 0
+}
+
+// correctionCapacity returns how many unknown errors a single RS block of this
+// version/level can correct. An erasure costs half as much as an error.
+func correctionCapacity(version Version, ecLevel ErrorCorrectionLevel) int {
+	ecInfo := getEcInfo(version, ecLevel)
+	return (ecInfo.ECCodewordsPerBlock - misdecodeProtection(version, ecLevel)) / 2
+}
+
+// correctBlock fixes a single Reed-Solomon block (data + EC codewords) in place.
+// Returns the amount of codewords that were corrected.
+//
+// erasures are indices into the block of codewords known to be unreliable.
+// Their position is known, so each one only costs half an error:
+// 2*errors + erasures has to stay within 2*maxErrors.
+//
+// Codeword i of the block is the coefficient of x^(n-1-i), same as
+// generateErrorCorrectionCodeWords produces it. The generator's roots are
+// α^0 .. α^(ecCount-1).
+func correctBlock(block []byte, ecCount int, maxErrors int, erasures []int) (int, error) {
+	if len(erasures) > 2*maxErrors {
+		return 0, fmt.Errorf("too many erasures in block (%d > %d)", len(erasures), 2*maxErrors)
+	}
+
+	syndromes, clean := calcSyndromes(block, ecCount)
+	if clean {
+		return 0, nil
+	}
+
+	n := len(block)
+	locator := berlekampMassey(syndromes, erasureLocator(erasures, n))
+	numErrata := len(locator) - 1
+	numErrors := numErrata - len(erasures)
+	if 2*numErrors+len(erasures) > 2*maxErrors {
+		return 0, fmt.Errorf("too many errors in block (%d errors, %d erasures, capacity %d)", numErrors, len(erasures), maxErrors)
+	}
+
+	positions := make([]int, 0, numErrata)
+	for i := range n {
+		xInv := gf256.Exp(byte((255 - (n-1-i)%255) % 255))
+		if polyEval(locator, xInv) == 0 {
+			positions = append(positions, i)
+		}
+	}
+	if len(positions) != numErrata {
+		return 0, fmt.Errorf("could not locate all errors in block")
+	}
+
+	// Ω(x) = S(x)Λ(x) mod x^ecCount
+	omega := make([]byte, ecCount)
+	for i, s := range syndromes {
+		for j, l := range locator {
+			if i+j < ecCount {
+				omega[i+j] ^= gf256.Multiply(s, l)
+			}
+		}
+	}
+
+	// Formal derivative; in GF(2^8) only the odd powers survive
+	derivative := make([]byte, len(locator))
+	for i := 1; i < len(locator); i += 2 {
+		derivative[i-1] = locator[i]
+	}
+
+	// Forney: e = X * Ω(X^-1) / Λ'(X^-1)
+	corrected := 0
+	for _, pos := range positions {
+		x := gf256.Exp(byte((n - 1 - pos) % 255))
+		xInv := gf256.Divide(1, x)
+		denom := polyEval(derivative, xInv)
+		if denom == 0 {
+			return 0, fmt.Errorf("could not compute error value in block")
+		}
+		// An erased codeword can turn out to be fine
+		if value := gf256.Multiply(x, gf256.Divide(polyEval(omega, xInv), denom)); value != 0 {
+			block[pos] ^= value
+			corrected++
+		}
+	}
+
+	if _, clean := calcSyndromes(block, ecCount); !clean {
+		return 0, fmt.Errorf("block still corrupt after correction")
+	}
+	return corrected, nil
+}
+
+// Γ(x) = Π (1 - X_j x) over the erased positions (lowest degree first)
+func erasureLocator(erasures []int, n int) []byte {
+	locator := []byte{1}
+	for _, pos := range erasures {
+		x := gf256.Exp(byte((n - 1 - pos) % 255))
+		next := make([]byte, len(locator)+1)
+		copy(next, locator)
+		for i, l := range locator {
+			next[i+1] ^= gf256.Multiply(l, x)
+		}
+		locator = next
+	}
+	return locator
+}
+
+// S_j = c(α^j) for j in [0, ecCount)
+func calcSyndromes(block []byte, ecCount int) ([]byte, bool) {
+	syndromes := make([]byte, ecCount)
+	clean := true
+
+	for j := range ecCount {
+		alpha := gf256.Exp(byte(j))
+		var s byte
+		for _, c := range block {
+			s = gf256.Multiply(s, alpha) ^ c
+		}
+		syndromes[j] = s
+		if s != 0 {
+			clean = false
+		}
+	}
+	return syndromes, clean
+}
+
+// Finds the errata locator polynomial Λ(x) (lowest degree first, Λ(0) = 1).
+// Starts from the erasure locator, so the result covers both the erasures and
+// the unknown errors. Without erasures this is plain Berlekamp-Massey.
+func berlekampMassey(syndromes []byte, erasureLocator []byte) []byte {
+	e := len(erasureLocator) - 1
+	curr := erasureLocator
+	prev := erasureLocator
+	l := e
+	m := 1
+	b := byte(1)
+
+	for k := e; k < len(syndromes); k++ {
+		d := byte(0)
+		for i := 0; i <= l && i < len(curr); i++ {
+			d ^= gf256.Multiply(curr[i], syndromes[k-i])
+		}
+
+		if d == 0 {
+			m++
+			continue
+		}
+
+		coef := gf256.Divide(d, b)
+		next := make([]byte, max(len(curr), len(prev)+m))
+		copy(next, curr)
+		for i, p := range prev {
+			next[i+m] ^= gf256.Multiply(coef, p)
+		}
+
+		if 2*l <= k+e {
+			prev = curr
+			l = k + 1 + e - l
+			b = d
+			m = 1
+		} else {
+			m++
+		}
+		curr = next
+	}
+
+	for len(curr) < l+1 {
+		curr = append(curr, 0)
+	}
+	return curr[:l+1]
+}
+
+// Evaluates a polynomial stored lowest degree first
+func polyEval(p []byte, x byte) byte {
+	var result byte
+	for i := len(p) - 1; i >= 0; i-- {
+		result = gf256.Multiply(result, x) ^ p[i]
+	}
+	return result
+}
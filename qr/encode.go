@@ -40,6 +40,14 @@ func getEncodingModeString(mode EncodingMode) string {
 	return "INVALID"
 }
 
+func (mode EncodingMode) String() string {
+	return getEncodingModeString(mode)
+}
+
+func (ecLevel ErrorCorrectionLevel) String() string {
+	return getErrorCorrectionString(ecLevel)
+}
+
 func getErrorCorrectionString(ecLevel ErrorCorrectionLevel) string {
 	switch ecLevel {
 	case EC_Low:
@@ -54,8 +62,40 @@ func getErrorCorrectionString(ecLevel ErrorCorrectionLevel) string {
 	return "INVALID"
 }
 
+type EncodeOptions struct {
+	EcLevel ErrorCorrectionLevel
+	Version int // 1-40, 0 picks the smallest version the input fits in
+	Verbose bool
+
+	// Decode the finished symbol (module matrix and rendered image) and
+	// fail unless it reads back exactly as the input. See Verify.
+	Verify bool
+
+	// Logo in the middle of the symbol. EcLevel and Version become minimums,
+	// they are raised until the logo is safe to draw (see Logo).
+	Logo *Logo
+}
+
 func GenerateQRCode(input string, ecLevel ErrorCorrectionLevel, versionOverride int, verboseFlag bool) *QRCode {
-	verbose = verboseFlag
+	qrCode, err := GenerateQRCodeWithOptions(input, EncodeOptions{
+		EcLevel: ecLevel,
+		Version: versionOverride,
+		Verbose: verboseFlag,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return qrCode
+}
+
+func GenerateQRCodeWithOptions(input string, opts EncodeOptions) (*QRCode, error) {
+	if opts.Logo != nil {
+		return generateWithLogo(input, opts)
+	}
+
+	verbose = opts.Verbose
+	ecLevel := opts.EcLevel
+	versionOverride := opts.Version
 	writer := bitwriter.New()
 
 	encodingMode := determineBestEncodingMode(input)
@@ -69,15 +109,18 @@ func GenerateQRCode(input string, ecLevel ErrorCorrectionLevel, versionOverride
 
 	charCount, err := getCharCount(encodingMode, input)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	// Determine the QR Code Version
 	version, err := determineMinQRVersion(charCount, ecLevel, encodingMode)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	if versionOverride > 0 {
+		if versionOverride > 40 || Version(versionOverride) < version {
+			return nil, fmt.Errorf("data does not fit in QR code version %d with this ErrorCorrection level (needs at least %d)", versionOverride, version)
+		}
 		version = Version(versionOverride)
 	}
 
@@ -96,7 +139,7 @@ func GenerateQRCode(input string, ecLevel ErrorCorrectionLevel, versionOverride
 	// Write/Encode the input string
 	err = writeString(writer, encodingMode, input)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	ecInfo := getEcInfo(version, ecLevel)
@@ -127,8 +170,15 @@ func GenerateQRCode(input string, ecLevel ErrorCorrectionLevel, versionOverride
 	finalMessage := getFinalMessage(dataCodeWords, ecInfo)
 
 	qrCode := New(version, ecLevel)
+	qrCode.EncodingMode = encodingMode
 	qrCode.ApplyFinalMessage(finalMessage)
-	return qrCode
+
+	if opts.Verify {
+		if err := qrCode.Verify([]byte(input)); err != nil {
+			return nil, err
+		}
+	}
+	return qrCode, nil
 }
 
 func getFinalMessage(dataCodeWords []byte, ecInfo ErrorCorrectionInfo) []byte {
@@ -0,0 +1,126 @@
+package qr
+
+import (
+	"aboutblank/qr-code/pdf"
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type EPSOptions struct {
+	ModuleSize float64 // Points per module, 0 = 0.5mm. pdf.MM converts from millimeters
+	QuietZone  *int    // Light modules around the symbol, nil = the standard 4, QuietZone(0) = none
+	Ink        Ink     // Color of the dark modules, zero = process black
+	Title      string
+}
+
+// Ink is a CMYK process color (components 0-1), or a spot color when Spot
+// names the separation. The CMYK values are then what devices without that
+// plate (proofs, screens) use instead.
+type Ink struct {
+	C, M, Y, K float64
+	Spot       string
+}
+
+var inkBlack = Ink{K: 1}
+
+// WriteEPS writes the symbol as Encapsulated PostScript for print workflows.
+// The dark areas are traced into outlines, one closed path per connected
+// shape instead of a square per module, so there are no hairline seams.
+// The light modules are left unprinted (paper). Logos are not drawn.
+func (qr *QRCode) WriteEPS(w io.Writer, opts EPSOptions) error {
+	moduleSize := opts.ModuleSize
+	if moduleSize <= 0 {
+		moduleSize = pdf.MM(defaultPDFModuleMM)
+	}
+	// Same precision as the numbers written, so the bounding box is exact
+	moduleSize = math.Round(moduleSize*1000) / 1000
+	quietZone := quietZoneOf(opts.QuietZone)
+
+	ink := opts.Ink
+	if ink == (Ink{}) {
+		ink = inkBlack
+	}
+	for _, v := range []float64{ink.C, ink.M, ink.Y, ink.K} {
+		if v < 0 || v > 1 {
+			return fmt.Errorf("CMYK components must be between 0 and 1, got %s", ink.cmyk())
+		}
+	}
+
+	total := qr.size + 2*quietZone
+	side := float64(total) * moduleSize
+	out := bufio.NewWriter(w)
+
+	out.WriteString("%!PS-Adobe-3.0 EPSF-3.0\n")
+	fmt.Fprintf(out, "%%%%BoundingBox: 0 0 %d %d\n", int(math.Ceil(side)), int(math.Ceil(side)))
+	fmt.Fprintf(out, "%%%%HiResBoundingBox: 0 0 %s %s\n", formatNum(side), formatNum(side))
+	out.WriteString("%%Creator: aboutblank/qr-code\n")
+	if opts.Title != "" {
+		fmt.Fprintf(out, "%%%%Title: %s\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(opts.Title))
+	}
+	out.WriteString("%%LanguageLevel: 2\n")
+	if ink.Spot != "" {
+		fmt.Fprintf(out, "%%%%DocumentCustomColors: %s\n", psString(ink.Spot))
+		fmt.Fprintf(out, "%%%%CMYKCustomColor: %s %s\n", ink.cmyk(), psString(ink.Spot))
+	} else {
+		fmt.Fprintf(out, "%%%%DocumentProcessColors: %s\n", ink.processColors())
+	}
+	out.WriteString("%%Pages: 0\n%%EndComments\n")
+
+	// Short names keep the file small, in a dictionary of our own so nothing
+	// leaks into the document the EPS gets placed in
+	out.WriteString("%%BeginProlog\n")
+	out.WriteString("/qrdict 3 dict def qrdict begin\n/m {moveto} bind def\n/l {lineto} bind def\n/h {closepath} bind def\nend\n")
+	out.WriteString("%%EndProlog\n")
+
+	out.WriteString("qrdict begin\ngsave\n")
+	if ink.Spot != "" {
+		// Tint transform: 1 tint -> c m y k
+		fmt.Fprintf(out, "[/Separation %s /DeviceCMYK {dup %s mul exch dup %s mul exch dup %s mul exch %s mul}] setcolorspace 1 setcolor\n",
+			psString(ink.Spot), formatNum(ink.C), formatNum(ink.M), formatNum(ink.Y), formatNum(ink.K))
+	} else {
+		fmt.Fprintf(out, "%s setcmykcolor\n", ink.cmyk())
+	}
+
+	// Module units from here on. PostScript's y axis points up, row 0 is at the top
+	fmt.Fprintf(out, "%s dup scale\nnewpath\n", formatNum(moduleSize))
+	for _, polygon := range qr.outlines() {
+		for i, p := range polygon {
+			op := "l"
+			if i == 0 {
+				op = "m"
+			}
+			fmt.Fprintf(out, "%d %d %s ", p.X+quietZone, total-p.Y-quietZone, op)
+		}
+		out.WriteString("h\n")
+	}
+	out.WriteString("fill\ngrestore\nend\nshowpage\n%%EOF\n")
+	return out.Flush()
+}
+
+func (ink Ink) cmyk() string {
+	return fmt.Sprintf("%s %s %s %s", formatNum(ink.C), formatNum(ink.M), formatNum(ink.Y), formatNum(ink.K))
+}
+
+// DSC names of the plates the ink actually uses
+func (ink Ink) processColors() string {
+	var plates []string
+	for i, v := range []float64{ink.C, ink.M, ink.Y, ink.K} {
+		if v > 0 {
+			plates = append(plates, []string{"Cyan", "Magenta", "Yellow", "Black"}[i])
+		}
+	}
+	return strings.Join(plates, " ")
+}
+
+// Rounded to 1/1000, without trailing zeros
+func formatNum(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+func psString(s string) string {
+	return "(" + strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s) + ")"
+}
@@ -0,0 +1,97 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestOutlines(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/eps", EC_Medium, 0, false)
+	polygons := qrCode.outlines()
+
+	// Even-odd fill of the outlines has to give back every module
+	for y := range qrCode.Size() {
+		for x := range qrCode.Size() {
+			if inside(polygons, float64(x)+0.5, float64(y)+0.5) != qrCode.At(x, y) {
+				t.Fatalf("module (%d,%d): outlines don't match the matrix", x, y)
+			}
+		}
+	}
+
+	// Merged: far fewer points than module corners
+	points := 0
+	for _, p := range polygons {
+		points += len(p)
+	}
+	if dark := countDark(qrCode); points >= dark*4 {
+		t.Errorf("%d outline points for %d dark modules, outlines aren't merged", points, dark)
+	}
+}
+
+func TestWriteEPS(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/eps", EC_Medium, 0, false)
+
+	var buf bytes.Buffer
+	err := qrCode.WriteEPS(&buf, EPSOptions{ModuleSize: 1.5, Ink: Ink{C: 1, M: 0.5, Spot: "PANTONE 286 C"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	// 33 modules at 1.5pt = 49.5pt, rounded up for the integer box
+	for _, want := range []string{
+		"%!PS-Adobe-3.0 EPSF-3.0\n",
+		"%%BoundingBox: 0 0 50 50\n",
+		"%%HiResBoundingBox: 0 0 49.5 49.5\n",
+		"%%CMYKCustomColor: 1 0.5 0 0 (PANTONE 286 C)\n",
+		"[/Separation (PANTONE 286 C) /DeviceCMYK",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+
+	buf.Reset()
+	if err := qrCode.WriteEPS(&buf, EPSOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "0 0 0 1 setcmykcolor") || !strings.Contains(buf.String(), "%%DocumentProcessColors: Black\n") {
+		t.Error("default ink should be process black")
+	}
+
+	if err := qrCode.WriteEPS(&buf, EPSOptions{Ink: Ink{K: 100}}); err == nil {
+		t.Error("expected an error for CMYK out of range")
+	}
+}
+
+// Crossing count of a ray going right from (px, py)
+func inside(polygons [][]image.Point, px, py float64) bool {
+	in := false
+	for _, polygon := range polygons {
+		for i, a := range polygon {
+			b := polygon[(i+1)%len(polygon)]
+			if a.X != b.X {
+				continue
+			}
+			lo, hi := min(a.Y, b.Y), max(a.Y, b.Y)
+			if float64(a.X) > px && py > float64(lo) && py < float64(hi) {
+				in = !in
+			}
+		}
+	}
+	return in
+}
+
+func countDark(qrCode *QRCode) int {
+	dark := 0
+	for y := range qrCode.Size() {
+		for x := range qrCode.Size() {
+			if qrCode.At(x, y) {
+				dark++
+			}
+		}
+	}
+	return dark
+}
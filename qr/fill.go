@@ -0,0 +1,199 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// Fill paints the dark modules with more than one color. Positions are
+// fractions of the symbol (without the quiet zone): (0, 0) is the top left
+// corner, (1, 1) the bottom right one. Use CheckFill to make sure every part
+// of it still has enough contrast.
+type Fill interface {
+	colorAt(u, v float64) color.RGBA
+	writeSVGDef(w io.Writer, id string, offset, size int) error
+	samples() []color.Color // Enough of the colors used to judge the contrast
+}
+
+// Gradient stops have to be in order of their offset
+type GradientStop struct {
+	Offset float64 // 0-1 along the gradient
+	Color  color.Color
+}
+
+// From (X0, Y0) to (X1, Y1), constant along the perpendicular
+type LinearGradient struct {
+	X0, Y0, X1, Y1 float64
+	Stops          []GradientStop
+}
+
+// Circles around (CX, CY), the last stop at radius R
+type RadialGradient struct {
+	CX, CY, R float64
+	Stops     []GradientStop
+}
+
+// The image stretched over the symbol, the module shapes clip it
+type ImageFill struct {
+	Image image.Image
+}
+
+// Points checked along a gradient
+const gradientSamples = 64
+
+// ===== Gradients =====
+
+func (g LinearGradient) colorAt(u, v float64) color.RGBA {
+	dx, dy := g.X1-g.X0, g.Y1-g.Y0
+	length := dx*dx + dy*dy
+	if length == 0 {
+		return stopColor(g.Stops, 0)
+	}
+	return stopColor(g.Stops, ((u-g.X0)*dx+(v-g.Y0)*dy)/length)
+}
+
+func (g RadialGradient) colorAt(u, v float64) color.RGBA {
+	if g.R <= 0 {
+		return stopColor(g.Stops, 1)
+	}
+	return stopColor(g.Stops, math.Hypot(u-g.CX, v-g.CY)/g.R)
+}
+
+func (g LinearGradient) samples() []color.Color { return gradientSamplesOf(g.Stops) }
+func (g RadialGradient) samples() []color.Color { return gradientSamplesOf(g.Stops) }
+
+func (g LinearGradient) writeSVGDef(w io.Writer, id string, offset, size int) error {
+	at := func(f float64) string { return formatNum(float64(offset) + f*float64(size)) }
+	fmt.Fprintf(w, `<linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="%s" y1="%s" x2="%s" y2="%s">`,
+		id, at(g.X0), at(g.Y0), at(g.X1), at(g.Y1))
+	writeSVGStops(w, g.Stops)
+	_, err := fmt.Fprint(w, "</linearGradient>")
+	return err
+}
+
+func (g RadialGradient) writeSVGDef(w io.Writer, id string, offset, size int) error {
+	at := func(f float64) string { return formatNum(float64(offset) + f*float64(size)) }
+	fmt.Fprintf(w, `<radialGradient id="%s" gradientUnits="userSpaceOnUse" cx="%s" cy="%s" r="%s">`,
+		id, at(g.CX), at(g.CY), formatNum(g.R*float64(size)))
+	writeSVGStops(w, g.Stops)
+	_, err := fmt.Fprint(w, "</radialGradient>")
+	return err
+}
+
+// Color at t (0-1), linear between the stops and flat past the ends
+func stopColor(stops []GradientStop, t float64) color.RGBA {
+	if len(stops) == 0 {
+		return rgba(nil, color.Black)
+	}
+	if t <= stops[0].Offset {
+		return rgba(stops[0].Color, color.Black)
+	}
+	for i := 1; i < len(stops); i++ {
+		a, b := stops[i-1], stops[i]
+		if t > b.Offset {
+			continue
+		}
+		f := 0.0
+		if b.Offset > a.Offset {
+			f = (t - a.Offset) / (b.Offset - a.Offset)
+		}
+		return blend(rgba(a.Color, color.Black), rgba(b.Color, color.Black), f)
+	}
+	return rgba(stops[len(stops)-1].Color, color.Black)
+}
+
+func gradientSamplesOf(stops []GradientStop) []color.Color {
+	colors := make([]color.Color, 0, gradientSamples+1)
+	for i := range gradientSamples + 1 {
+		colors = append(colors, stopColor(stops, float64(i)/gradientSamples))
+	}
+	return colors
+}
+
+func writeSVGStops(w io.Writer, stops []GradientStop) {
+	for _, stop := range stops {
+		c := color.NRGBAModel.Convert(rgba(stop.Color, color.Black)).(color.NRGBA)
+		fmt.Fprintf(w, `<stop offset="%s" stop-color="#%02x%02x%02x"`, formatNum(stop.Offset), c.R, c.G, c.B)
+		if c.A != 255 {
+			fmt.Fprintf(w, ` stop-opacity="%s"`, formatNum(float64(c.A)/255))
+		}
+		fmt.Fprint(w, "/>")
+	}
+}
+
+// ===== Image =====
+
+func (f ImageFill) colorAt(u, v float64) color.RGBA {
+	b := f.Image.Bounds()
+	x := b.Min.X + min(max(int(u*float64(b.Dx())), 0), b.Dx()-1)
+	y := b.Min.Y + min(max(int(v*float64(b.Dy())), 0), b.Dy()-1)
+	return rgba(f.Image.At(x, y), color.Black)
+}
+
+// Every pixel of small images, a grid over big ones
+func (f ImageFill) samples() []color.Color {
+	b := f.Image.Bounds()
+	step := max(b.Dx()/gradientSamples, b.Dy()/gradientSamples, 1)
+	var colors []color.Color
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		for x := b.Min.X; x < b.Max.X; x += step {
+			colors = append(colors, f.Image.At(x, y))
+		}
+	}
+	return colors
+}
+
+func (f ImageFill) writeSVGDef(w io.Writer, id string, offset, size int) error {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, f.Image); err != nil {
+		return fmt.Errorf("failed to encode fill image: %w", err)
+	}
+	fmt.Fprintf(w, `<pattern id="%s" patternUnits="userSpaceOnUse" x="%d" y="%d" width="%d" height="%d">`, id, offset, offset, size, size)
+	fmt.Fprintf(w, `<image width="%d" height="%d" preserveAspectRatio="none" href="data:image/png;base64,%s"/>`,
+		size, size, base64.StdEncoding.EncodeToString(encoded.Bytes()))
+	_, err := fmt.Fprint(w, "</pattern>")
+	return err
+}
+
+// ===== Checking and drawing =====
+
+// CheckFill is CheckColors for every part of a fill: it fails if any color
+// the fill uses is inverted or too low in contrast against bg.
+func CheckFill(fill Fill, bg color.Color) error {
+	worst, worstContrast := color.Color(nil), math.Inf(1)
+	for _, c := range fill.samples() {
+		if contrast := ColorContrast(c, bg).Value; contrast < worstContrast {
+			worst, worstContrast = c, contrast
+		}
+	}
+	if worst == nil {
+		return nil
+	}
+	if err := CheckColors(worst, bg); err != nil {
+		return fmt.Errorf("part of the fill: %w", err)
+	}
+	return nil
+}
+
+// Recolors the dark modules, mask holds their coverage in its alpha channel
+func (qr *QRCode) drawFill(img, mask *image.RGBA, fill Fill, quietZone, scale int, light color.RGBA) {
+	side := float64(qr.size * scale)
+	origin := float64(quietZone * scale)
+	b := img.Bounds()
+	for py := b.Min.Y; py < b.Max.Y; py++ {
+		for px := b.Min.X; px < b.Max.X; px++ {
+			coverage := mask.RGBAAt(px, py).A
+			if coverage == 0 {
+				continue
+			}
+			c := fill.colorAt((float64(px)+0.5-origin)/side, (float64(py)+0.5-origin)/side)
+			img.SetRGBA(px, py, blend(light, c, float64(coverage)/255))
+		}
+	}
+}
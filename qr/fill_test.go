@@ -0,0 +1,106 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+var (
+	navy   = color.RGBA{0x1a, 0x23, 0x7e, 0xff}
+	purple = color.RGBA{0x6a, 0x1b, 0x9a, 0xff}
+)
+
+func TestGradientFill(t *testing.T) {
+	content := "https://example.com/gradient"
+	qrCode := GenerateQRCode(content, EC_Medium, 0, false)
+	fill := LinearGradient{X1: 1, Y1: 1, Stops: []GradientStop{{0, navy}, {1, purple}}}
+
+	img := qrCode.GenerateImageWithOptions(ImageOptions{Scale: 4, Fill: fill, ModuleStyle: ModuleStyle_Rounded})
+	if err := VerifyImage(img, []byte(content)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Finder corners: top left is the first stop, bottom left halfway
+	if got := img.RGBAAt(4*4, 4*4); got != navy {
+		t.Errorf("top left is %v, expected %v", got, navy)
+	}
+	size := qrCode.Size()
+	if got, want := img.RGBAAt(4*4, (4+size)*4-1), fill.colorAt(0, 1); got != want {
+		t.Errorf("bottom left is %v, expected %v", got, want)
+	}
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("quiet zone is %v, the fill should only color dark modules", got)
+	}
+
+	// Invert is ignored with a fill, the centering background too
+	centered := qrCode.GenerateImageWithOptions(ImageOptions{Size: 300, Center: true, Invert: true, Fill: fill})
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	if corner, quiet := centered.RGBAAt(0, 0), centered.RGBAAt(10, 10); corner != white || quiet != white {
+		t.Errorf("centered and inverted: corner %v, quiet zone %v, expected both white", corner, quiet)
+	}
+	if err := VerifyImage(centered, []byte(content)); err != nil {
+		t.Error(err)
+	}
+
+	if err := CheckFill(fill, color.White); err != nil {
+		t.Error(err)
+	}
+	fading := RadialGradient{CX: 0.5, CY: 0.5, R: 0.7, Stops: []GradientStop{{0, navy}, {1, color.RGBA{0xff, 0xeb, 0x3b, 0xff}}}}
+	if err := CheckFill(fading, color.White); err == nil {
+		t.Error("a gradient into yellow on white should fail the contrast check")
+	}
+}
+
+func TestImageFill(t *testing.T) {
+	content := "https://example.com/image-fill"
+	qrCode := GenerateQRCode(content, EC_Medium, 0, false)
+
+	// Dark stripes
+	stripes := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := range 8 {
+		for x := range 8 {
+			c := navy
+			if (x+y)%4 < 2 {
+				c = purple
+			}
+			stripes.SetRGBA(x, y, c)
+		}
+	}
+
+	img := qrCode.GenerateImageWithOptions(ImageOptions{Scale: 4, Fill: ImageFill{stripes}})
+	if err := VerifyImage(img, []byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckFill(ImageFill{stripes}, color.White); err != nil {
+		t.Error(err)
+	}
+
+	var buf bytes.Buffer
+	if err := qrCode.WriteSVG(&buf, SVGOptions{Fill: ImageFill{stripes}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<pattern id="qr-fill"`) || !strings.Contains(buf.String(), `fill="url(#qr-fill)"`) {
+		t.Error("SVG should define the image as a pattern and fill with it")
+	}
+}
+
+func TestGradientSVG(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/gradient", EC_Medium, 0, false)
+	fill := LinearGradient{X1: 1, Stops: []GradientStop{{0, navy}, {1, purple}}}
+
+	var buf bytes.Buffer
+	if err := qrCode.WriteSVG(&buf, SVGOptions{Fill: fill}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	// In viewBox units: from the symbol's left edge to its right edge
+	want := `<linearGradient id="qr-fill" gradientUnits="userSpaceOnUse" x1="4" y1="4" x2="33" y2="4">` +
+		`<stop offset="0" stop-color="#1a237e"/><stop offset="1" stop-color="#6a1b9a"/></linearGradient>`
+	if !strings.Contains(out, want) {
+		t.Errorf("missing gradient definition, got:\n%s", out[:min(len(out), 600)])
+	}
+}
@@ -0,0 +1,421 @@
+package qr
+
+import (
+	"image"
+	"math"
+)
+
+// ISO/IEC 15415 style grade, A (best) to F. There is no E.
+type Grade int
+
+const (
+	Grade_F Grade = iota
+	Grade_D
+	Grade_C
+	Grade_B
+	Grade_A
+)
+
+func (g Grade) String() string {
+	return string("FDCBA"[g])
+}
+
+type Measurement struct {
+	Value float64
+	Grade Grade
+}
+
+// Damage of one group of fixed pattern modules
+type PatternDamage struct {
+	Name    string
+	Modules int
+	Damaged int // Modules on the wrong side of the global threshold
+	Grade   Grade
+}
+
+// Print quality of a single scanned symbol, following ISO/IEC 15415 and the
+// QR specific parameters of ISO/IEC 18004. Reflectances are in percent,
+// measured from the image's luminance at the center of every module.
+type QualityReport struct {
+	*DecodeResult
+
+	Rmax, Rmin      float64
+	GlobalThreshold float64 // Halfway between Rmax and Rmin
+
+	SymbolContrast     Measurement // Rmax - Rmin
+	Modulation         Measurement // Value: lowest modulation of any data module
+	ReflectanceMargin  Measurement // Value: lowest margin of any data module, 0 if a module is wrong
+	FixedPatternDamage Grade
+	Patterns           []PatternDamage
+	AxialNonUniformity Measurement
+	GridNonUniformity  Measurement // Value: largest deviation from the ideal grid, in modules
+	UnusedEC           Measurement // Value: lowest over all blocks
+	Decode             Grade
+
+	Overall Grade
+}
+
+// Grade thresholds for A, B, C and D, anything worse is F
+var (
+	contrastGrades      = [4]float64{70, 55, 40, 20}
+	modulationGrades    = [4]float64{0.50, 0.40, 0.30, 0.20}
+	unusedECGrades      = [4]float64{0.62, 0.50, 0.37, 0.25}
+	axialGrades         = [4]float64{0.06, 0.08, 0.10, 0.12}
+	gridGrades          = [4]float64{0.38, 0.50, 0.63, 0.75}
+	patternPercentGrade = [4]float64{0, 0.07, 0.11, 0.14}
+)
+
+// Higher is better
+func gradeAtLeast(value float64, thresholds [4]float64) Grade {
+	for i, t := range thresholds {
+		if value >= t {
+			return Grade_A - Grade(i)
+		}
+	}
+	return Grade_F
+}
+
+// Lower is better
+func gradeAtMost(value float64, thresholds [4]float64) Grade {
+	for i, t := range thresholds {
+		if value <= t {
+			return Grade_A - Grade(i)
+		}
+	}
+	return Grade_F
+}
+
+// GradeImage decodes every QR code in img and grades its print quality.
+// ISO/IEC 15415 averages several scans, this grades a single one.
+func GradeImage(img image.Image) ([]*QualityReport, error) {
+	lum := toLuminance(img)
+	results, err := decodeLuminance(lum, DecodeOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	bits := binarize(lum)
+	inverted := binarize(lum)
+	inverted.invert()
+
+	reports := make([]*QualityReport, 0, len(results))
+	for _, r := range results {
+		b := bits
+		if r.Inverted {
+			b = inverted
+		}
+		reports = append(reports, gradeSymbol(lum, b, r))
+	}
+	return reports, nil
+}
+
+// Average luminance over a round aperture of 0.8 modules, in percent
+func (lum *lumImage) reflectance(t perspective, x, y float64) float64 {
+	offsets := [4]float64{-0.3, -0.1, 0.1, 0.3}
+	total := 0.0
+	for _, dx := range offsets {
+		for _, dy := range offsets {
+			p := t.transform(x+dx, y+dy)
+			px := min(max(int(math.Floor(p.X)), 0), lum.w-1)
+			py := min(max(int(math.Floor(p.Y)), 0), lum.h-1)
+			total += float64(lum.pix[py*lum.w+px])
+		}
+	}
+	return total / 16 * 100 / 255
+}
+
+func gradeSymbol(lum *lumImage, b *bitImage, r *DecodeResult) *QualityReport {
+	report := &QualityReport{DecodeResult: r, Decode: Grade_A}
+	symbol := r.symbol()
+	size := symbol.size
+	t := r.transform
+
+	// Reflectance of every module, in symbol coordinates
+	refl := make([][]float64, size)
+	for x := range refl {
+		refl[x] = make([]float64, size)
+	}
+	report.Rmax, report.Rmin = 0, 100
+	for x := range size {
+		for y := range size {
+			value := lum.reflectance(t, float64(x)+0.5, float64(y)+0.5)
+			if r.Mirrored {
+				refl[y][x] = value
+			} else {
+				refl[x][y] = value
+			}
+			report.Rmax = max(report.Rmax, value)
+			report.Rmin = min(report.Rmin, value)
+		}
+	}
+
+	// The quiet zone right around the symbol counts for Rmax too
+	for i := -1; i <= size; i++ {
+		for _, p := range [4][2]int{{i, -1}, {i, size}, {-1, i}, {size, i}} {
+			c := t.transform(float64(p[0])+0.5, float64(p[1])+0.5)
+			if c.X >= 0 && c.Y >= 0 && c.X < float64(lum.w) && c.Y < float64(lum.h) {
+				report.Rmax = max(report.Rmax, lum.reflectance(t, float64(p[0])+0.5, float64(p[1])+0.5))
+			}
+		}
+	}
+
+	sc := max(report.Rmax-report.Rmin, 0)
+	gt := (report.Rmax + report.Rmin) / 2
+	report.GlobalThreshold = gt
+	report.SymbolContrast = Measurement{sc, gradeAtLeast(sc, contrastGrades)}
+
+	// Modulation and reflectance margin of every module
+	mod := make([][]float64, size)
+	margin := make([][]float64, size)
+	for x := range size {
+		mod[x] = make([]float64, size)
+		margin[x] = make([]float64, size)
+		for y := range size {
+			if sc > 0 {
+				mod[x][y] = 2 * math.Abs(refl[x][y]-gt) / sc
+			}
+			appearsDark := (refl[x][y] < gt) != r.Inverted
+			if appearsDark == (symbol.getModule(x, y).Value == ValueBlack) {
+				margin[x][y] = mod[x][y]
+			}
+		}
+	}
+
+	report.Modulation = report.gradeCodewords(symbol, mod)
+	report.ReflectanceMargin = report.gradeCodewords(symbol, margin)
+
+	report.FixedPatternDamage = Grade_A
+	for _, pattern := range symbol.fixedPatterns() {
+		damage := gradePattern(pattern, margin)
+		report.Patterns = append(report.Patterns, damage)
+		report.FixedPatternDamage = min(report.FixedPatternDamage, damage.Grade)
+	}
+
+	report.gradeGeometry(b, t, size)
+
+	// Errors cost two codewords of EC capacity
+	ecInfo := getEcInfo(r.Version, r.EcLevel)
+	capacity := float64(ecInfo.ECCodewordsPerBlock - misdecodeProtection(r.Version, r.EcLevel))
+	uec := 1.0
+	for _, n := range r.ErrorsCorrected {
+		uec = min(uec, max(1-2*float64(n)/capacity, 0))
+	}
+	report.UnusedEC = Measurement{uec, gradeAtLeast(uec, unusedECGrades)}
+
+	report.Overall = min(report.Decode, report.SymbolContrast.Grade, report.Modulation.Grade,
+		report.ReflectanceMargin.Grade, report.FixedPatternDamage, report.AxialNonUniformity.Grade,
+		report.GridNonUniformity.Grade, report.UnusedEC.Grade)
+	return report
+}
+
+// Codeword based grading of a per-module value (modulation or margin).
+// A codeword is as good as its worst module. For every grade level the
+// codewords below it are treated as erasures; the level only counts if the EC
+// that would be left is still good enough. A block gets the best level that
+// holds up, the symbol its worst block.
+func (report *QualityReport) gradeCodewords(symbol *QRCode, values [][]float64) Measurement {
+	ecInfo := getEcInfo(report.Version, report.EcLevel)
+	capacity := float64(ecInfo.ECCodewordsPerBlock - misdecodeProtection(report.Version, report.EcLevel))
+	positions := symbol.dataPositions()
+
+	lowest := math.Inf(1)
+	blockGrades := make([][]Grade, ecInfo.TotalBlocks())
+	for i, pos := range interleaveOrder(ecInfo) {
+		grade := Grade_A
+		for _, p := range positions[i*8 : i*8+8] {
+			v := values[p[0]][p[1]]
+			lowest = min(lowest, v)
+			grade = min(grade, gradeAtLeast(v, modulationGrades))
+		}
+		blockGrades[pos[0]] = append(blockGrades[pos[0]], grade)
+	}
+
+	overall := Grade_A
+	for _, grades := range blockGrades {
+		best := Grade_F
+		for level := Grade_A; level > Grade_F; level-- {
+			erasures := 0
+			for _, g := range grades {
+				if g < level {
+					erasures++
+				}
+			}
+			uec := 1 - float64(erasures)/capacity
+			best = max(best, min(level, gradeAtLeast(uec, unusedECGrades)))
+		}
+		overall = min(overall, best)
+	}
+
+	return Measurement{lowest, overall}
+}
+
+type fixedPattern struct {
+	name    string
+	modules [][2]int
+	finder  bool // Graded by the number of damaged modules instead of the percentage
+}
+
+// Finders with their separators, the timing patterns and the alignment patterns
+func (qr *QRCode) fixedPatterns() []fixedPattern {
+	size := qr.size
+	var patterns []fixedPattern
+
+	for _, finder := range []struct {
+		name   string
+		x0, y0 int
+	}{{"top-left finder", 0, 0}, {"top-right finder", size - 8, 0}, {"bottom-left finder", 0, size - 8}} {
+		pattern := fixedPattern{name: finder.name, finder: true}
+		for x := range 8 {
+			for y := range 8 {
+				pattern.modules = append(pattern.modules, [2]int{finder.x0 + x, finder.y0 + y})
+			}
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	timing := fixedPattern{name: "timing"}
+	for i := 8; i < size-8; i++ {
+		timing.modules = append(timing.modules, [2]int{i, 6}, [2]int{6, i})
+	}
+	patterns = append(patterns, timing)
+
+	if origins := qr.alignmentOrigins(); len(origins) > 0 {
+		alignment := fixedPattern{name: "alignment"}
+		for _, o := range origins {
+			for x := range 5 {
+				for y := range 5 {
+					alignment.modules = append(alignment.modules, [2]int{o[0] + x, o[1] + y})
+				}
+			}
+		}
+		patterns = append(patterns, alignment)
+	}
+
+	return patterns
+}
+
+// Top-left corners of the alignment patterns that actually got placed
+func (qr *QRCode) alignmentOrigins() [][2]int {
+	tmpl := New(qr.Version, qr.EcLevel)
+	tmpl.AddFinderPatternsAndSeparators()
+
+	var origins [][2]int
+	for _, x := range alignmentPatternPositions[qr.Version] {
+		for _, y := range alignmentPatternPositions[qr.Version] {
+			if tmpl.addAlignmentPattern(x, y) {
+				origins = append(origins, [2]int{x, y})
+			}
+		}
+	}
+	return origins
+}
+
+// Same idea as gradeCodewords: for every level, the modules below it count as
+// damaged, and the level holds if the damage is small enough.
+func gradePattern(pattern fixedPattern, margin [][]float64) PatternDamage {
+	damage := PatternDamage{Name: pattern.name, Modules: len(pattern.modules)}
+
+	for _, p := range pattern.modules {
+		if margin[p[0]][p[1]] == 0 {
+			damage.Damaged++
+		}
+	}
+
+	for level := Grade_A; level > Grade_F; level-- {
+		below := 0
+		for _, p := range pattern.modules {
+			if gradeAtLeast(margin[p[0]][p[1]], modulationGrades) < level {
+				below++
+			}
+		}
+
+		var g Grade
+		if pattern.finder {
+			g = Grade(max(int(Grade_A)-below, int(Grade_F)))
+		} else {
+			g = gradeAtMost(float64(below)/float64(len(pattern.modules)), patternPercentGrade)
+		}
+		damage.Grade = max(damage.Grade, min(level, g))
+	}
+	return damage
+}
+
+// Axial non-uniformity compares the module pitch along both axes, measured
+// between the finder centers. Grid non-uniformity is the largest distance of
+// an alignment pattern center from where a perfectly regular grid through the
+// finders puts it. Works in the sampled grid's coordinates, mirroring doesn't
+// matter for either.
+func (report *QualityReport) gradeGeometry(b *bitImage, t perspective, size int) {
+	dim := float64(size)
+	between := dim - 7
+	tl := t.transform(3.5, 3.5)
+	tr := t.transform(dim-3.5, 3.5)
+	bl := t.transform(3.5, dim-3.5)
+
+	pitchX := distance(tl, tr) / between
+	pitchY := distance(tl, bl) / between
+	an := math.Abs(pitchX-pitchY) / ((pitchX + pitchY) / 2)
+	report.AxialNonUniformity = Measurement{an, gradeAtMost(an, axialGrades)}
+
+	moduleSize := (pitchX + pitchY) / 2
+	ex := Point{(tr.X - tl.X) / between, (tr.Y - tl.Y) / between}
+	ey := Point{(bl.X - tl.X) / between, (bl.Y - tl.Y) / between}
+
+	gn := 0.0
+	for _, o := range New(report.Version, report.EcLevel).alignmentOrigins() {
+		cx, cy := float64(o[0])+2.5, float64(o[1])+2.5
+		ideal := Point{tl.X + (cx-3.5)*ex.X + (cy-3.5)*ey.X, tl.Y + (cx-3.5)*ex.Y + (cy-3.5)*ey.Y}
+
+		// Local module vectors, the symbol may be warped
+		c := t.transform(cx, cy)
+		right, down := t.transform(cx+1, cy), t.transform(cx, cy+1)
+		lx := Point{right.X - c.X, right.Y - c.Y}
+		ly := Point{down.X - c.X, down.Y - c.Y}
+
+		if actual, ok := b.locateAlignment(c, lx, ly, moduleSize); ok {
+			gn = max(gn, distance(actual, ideal)/moduleSize)
+		}
+	}
+	report.GridNonUniformity = Measurement{gn, gradeAtMost(gn, gridGrades)}
+}
+
+// Finds the center of an alignment pattern near estimate. Every position where
+// the whole 5x5 template matches best is averaged, which gives the center with
+// sub-pixel precision instead of the first hit.
+func (b *bitImage) locateAlignment(estimate, ex, ey Point, moduleSize float64) (Point, bool) {
+	step := max(0.5, moduleSize/8)
+	radius := 2 * moduleSize
+
+	bestScore := 0
+	var sumX, sumY, count float64
+	for dy := -radius; dy <= radius; dy += step {
+		for dx := -radius; dx <= radius; dx += step {
+			center := Point{estimate.X + dx, estimate.Y + dy}
+			score := 0
+			for i := range 5 {
+				for j := range 5 {
+					x := center.X + float64(i-2)*ex.X + float64(j-2)*ey.X
+					y := center.Y + float64(i-2)*ex.Y + float64(j-2)*ey.Y
+					if b.at(int(math.Floor(x)), int(math.Floor(y))) == alignmentPattern[i][j] {
+						score++
+					}
+				}
+			}
+
+			if score > bestScore {
+				bestScore = score
+				sumX, sumY, count = 0, 0, 0
+			}
+			if score == bestScore {
+				sumX += center.X
+				sumY += center.Y
+				count++
+			}
+		}
+	}
+
+	if bestScore < 23 {
+		return Point{}, false
+	}
+	return Point{sumX / count, sumY / count}, true
+}
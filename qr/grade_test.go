@@ -0,0 +1,83 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestGradeClean(t *testing.T) {
+	img := GenerateQRCode("https://example.com/grade", EC_Medium, 0, false).GenerateImage(6)
+	reports, err := GradeImage(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := reports[0]
+	if r.Overall != Grade_A {
+		t.Errorf("clean symbol graded %s: %+v", r.Overall, r)
+	}
+	if len(r.Patterns) != 5 {
+		t.Errorf("expected 3 finders, timing and alignment, got %d patterns", len(r.Patterns))
+	}
+}
+
+func TestGradeDefects(t *testing.T) {
+	img := GenerateQRCode("https://example.com/grade", EC_Medium, 0, false).GenerateImage(6)
+	bounds := img.Bounds()
+
+	// Gray ink, and 10% wider than it should be
+	out := image.NewRGBA(image.Rect(0, 0, bounds.Dx()*11/10, bounds.Dy()))
+	for y := range bounds.Dy() {
+		for x := range out.Bounds().Dx() {
+			c := img.RGBAAt(x*10/11, y)
+			if c.R == 0 {
+				c = color.RGBA{140, 140, 140, 255}
+			}
+			out.SetRGBA(x, y, c)
+		}
+	}
+
+	reports, err := GradeImage(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := reports[0]
+	if r.SymbolContrast.Grade != Grade_C {
+		t.Errorf("symbol contrast %.1f graded %s, expected C", r.SymbolContrast.Value, r.SymbolContrast.Grade)
+	}
+	if r.AxialNonUniformity.Grade >= Grade_B {
+		t.Errorf("axial non-uniformity %.3f graded %s, expected C or worse", r.AxialNonUniformity.Value, r.AxialNonUniformity.Grade)
+	}
+	if r.Overall > Grade_C {
+		t.Errorf("overall grade %s, expected C or worse", r.Overall)
+	}
+}
+
+func TestGradeUnusedEC(t *testing.T) {
+	qrCode := GenerateQRCode("HELLO", EC_High, 1, false)
+	// Flip a whole codeword worth of modules in the data area
+	for _, pos := range qrCode.dataPositions()[:8] {
+		mod := qrCode.getModule(pos[0], pos[1])
+		if mod.Value == ValueBlack {
+			mod.Value = ValueWhite
+		} else {
+			mod.Value = ValueBlack
+		}
+	}
+
+	reports, err := GradeImage(qrCode.GenerateImage(6))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1-H has 17 EC codewords and p = 1, one error leaves 1 - 2/16 of it
+	r := reports[0]
+	if r.UnusedEC.Value != 0.875 {
+		t.Errorf("unused EC %.3f, expected 0.875", r.UnusedEC.Value)
+	}
+	if r.ReflectanceMargin.Value != 0 {
+		t.Errorf("wrong modules should have no reflectance margin, got %.2f", r.ReflectanceMargin.Value)
+	}
+}
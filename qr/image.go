@@ -0,0 +1,61 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+)
+
+// SymbolImage is an image.PalettedImage that reads its pixels straight from
+// the module matrix, nothing is rasterized up front. Hand it to png.Encode or
+// draw.Draw like any other image. Logos are not drawn, use GenerateImage for those.
+type SymbolImage struct {
+	qr        *QRCode
+	Scale     int  // Pixels per module
+	QuietZone int  // Light modules around the symbol
+	Invert    bool // Light modules on a dark background
+}
+
+// Palette indices of SymbolImage
+const (
+	Index_Light uint8 = 0
+	Index_Dark  uint8 = 1
+)
+
+var (
+	symbolPalette         = color.Palette{color.White, color.Black}
+	invertedSymbolPalette = color.Palette{color.Black, color.White}
+)
+
+func (qr *QRCode) Image(scale, quietZone int) *SymbolImage {
+	return &SymbolImage{qr: qr, Scale: max(scale, 1), QuietZone: max(quietZone, 0)}
+}
+
+func (img *SymbolImage) ColorModel() color.Model {
+	return img.palette()
+}
+
+func (img *SymbolImage) palette() color.Palette {
+	if img.Invert {
+		return invertedSymbolPalette
+	}
+	return symbolPalette
+}
+
+func (img *SymbolImage) Bounds() image.Rectangle {
+	side := (img.qr.size + 2*img.QuietZone) * img.Scale
+	return image.Rect(0, 0, side, side)
+}
+
+func (img *SymbolImage) At(x, y int) color.Color {
+	return img.palette()[img.ColorIndexAt(x, y)]
+}
+
+func (img *SymbolImage) ColorIndexAt(x, y int) uint8 {
+	if !image.Pt(x, y).In(img.Bounds()) {
+		return Index_Light
+	}
+	if img.qr.At(x/img.Scale-img.QuietZone, y/img.Scale-img.QuietZone) {
+		return Index_Dark
+	}
+	return Index_Light
+}
@@ -0,0 +1,126 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+)
+
+var _ image.PalettedImage = (*SymbolImage)(nil)
+
+func TestSymbolImageMatchesGenerateImage(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/adapter", EC_Medium, 0, false)
+
+	for _, invert := range []bool{false, true} {
+		adapter := qrCode.Image(3, 4)
+		adapter.Invert = invert
+		rendered := qrCode.GenerateImageWithOptions(ImageOptions{Scale: 3, Invert: invert})
+
+		if adapter.Bounds() != rendered.Bounds() {
+			t.Fatalf("bounds %v, expected %v", adapter.Bounds(), rendered.Bounds())
+		}
+
+		bounds := rendered.Bounds()
+		for y := range bounds.Dy() {
+			for x := range bounds.Dx() {
+				r1, g1, b1, a1 := adapter.At(x, y).RGBA()
+				r2, g2, b2, a2 := rendered.At(x, y).RGBA()
+				if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+					t.Fatalf("invert=%t: pixel (%d,%d) differs", invert, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestSymbolImageEncodes(t *testing.T) {
+	qrCode := GenerateQRCode("HELLO", EC_Low, 0, false)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, qrCode.Image(2, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.(*image.Paletted); !ok {
+		t.Errorf("expected a paletted PNG, got %T", decoded)
+	}
+	if size := decoded.Bounds().Dx(); size != 42 {
+		t.Errorf("image is %d pixels wide, expected 42", size)
+	}
+
+	matrix := qrCode.BitMatrix()
+	for x := range qrCode.Size() {
+		for y := range qrCode.Size() {
+			if qrCode.At(x, y) != matrix[x][y] {
+				t.Fatalf("At(%d,%d) doesn't match the bit matrix", x, y)
+			}
+		}
+	}
+	if qrCode.At(-1, 0) || qrCode.At(0, qrCode.Size()) {
+		t.Error("outside the symbol should be light")
+	}
+}
+
+func TestGenerateImageSize(t *testing.T) {
+	content := "https://example.com/size"
+	qrCode := GenerateQRCode(content, EC_Medium, 0, false) // 25 modules, 33 with the quiet zone
+
+	cases := []struct {
+		name string
+		opts ImageOptions
+		side int
+	}{
+		{"no quiet zone", ImageOptions{Scale: 3, QuietZone: QuietZone(0)}, 25 * 3},
+		{"quiet zone 2", ImageOptions{Scale: 3, QuietZone: QuietZone(2)}, 29 * 3},
+		{"largest whole scale", ImageOptions{Size: 300}, 33 * 9},
+		{"centered", ImageOptions{Size: 300, Center: true}, 300},
+		{"smooth", ImageOptions{Size: 300, Smooth: true}, 300},
+		{"smooth upscale", ImageOptions{Size: 100, Smooth: true, QuietZone: QuietZone(1)}, 100},
+		{"below a pixel per module", ImageOptions{Size: 20}, 20},
+		{"below a pixel per module, centered", ImageOptions{Size: 20, Center: true}, 20},
+	}
+
+	for _, c := range cases {
+		img := qrCode.GenerateImageWithOptions(c.opts)
+		if side := img.Bounds().Dx(); side != c.side || img.Bounds().Dy() != c.side {
+			t.Errorf("%s: %v, expected %dx%d", c.name, img.Bounds(), c.side, c.side)
+			continue
+		}
+		// Nothing for the detector to go on without a quiet zone or with
+		// modules under a pixel
+		if quietZoneOf(c.opts.QuietZone) == 0 || c.side < 33 {
+			continue
+		}
+		if err := VerifyImage(img, []byte(content)); err != nil {
+			t.Errorf("%s: %v", c.name, err)
+		}
+	}
+
+	// Centered: the symbol's first dark pixel is offset by half the leftover
+	img := qrCode.GenerateImageWithOptions(ImageOptions{Size: 300, Center: true})
+	offset := (300 - 33*9) / 2
+	if r, _, _, _ := img.At(offset+4*9, offset+4*9).RGBA(); r != 0 {
+		t.Error("finder corner isn't where centering should put it")
+	}
+	if r, _, _, _ := img.At(offset+4*9-1, offset+4*9-1).RGBA(); r != 0xffff {
+		t.Error("quiet zone next to the finder should be light")
+	}
+
+	// Smooth: module edges that fall inside a pixel blend
+	smooth := qrCode.GenerateImageWithOptions(ImageOptions{Size: 300, Smooth: true})
+	gray := false
+	for x := range 300 {
+		if r, _, _, _ := smooth.At(x, 150).RGBA(); r != 0 && r != 0xffff {
+			gray = true
+			break
+		}
+	}
+	if !gray {
+		t.Error("smooth scaling should anti-alias module edges")
+	}
+}
@@ -0,0 +1,115 @@
+package qr
+
+import (
+	"image"
+)
+
+// Everything there is to know about an existing symbol
+type Inspection struct {
+	*DecodeResult
+
+	// Penalty score of every mask, as this encoder scores them
+	MaskScores [8]int
+
+	DataBits     int  // Bits used by the segments (before the terminator)
+	CapacityBits int  // Data bits the version/EC level can hold
+	PadBytes     int  // Pad codewords after the data
+	PadPattern   bool // The pad codewords follow the standard 0xEC 0x11 pattern
+
+	// What this encoder makes of the same content at the same EC level
+	EncoderVersion Version
+	EncoderMask    int
+	EncoderMode    EncodingMode
+	// Modules that differ from this encoder's output at the same version
+	// and EC level. -1 if the content doesn't fit that version.
+	ModulesDiffer int
+}
+
+// Symbol is identical to what this encoder would produce for the same content
+func (i *Inspection) MatchesEncoder() bool {
+	return i.EncoderVersion == i.Version && i.ModulesDiffer == 0
+}
+
+func (i *Inspection) CorrectionCapacity() int {
+	return correctionCapacity(i.Version, i.EcLevel)
+}
+
+// InspectImage decodes and inspects every QR code in img
+func InspectImage(img image.Image) ([]*Inspection, error) {
+	results, err := DecodeImage(img)
+	if err != nil {
+		return nil, err
+	}
+
+	inspections := make([]*Inspection, 0, len(results))
+	for _, r := range results {
+		inspections = append(inspections, Inspect(r))
+	}
+	return inspections, nil
+}
+
+func Inspect(result *DecodeResult) *Inspection {
+	ecInfo := getEcInfo(result.Version, result.EcLevel)
+	inspection := &Inspection{
+		DecodeResult:  result,
+		CapacityBits:  ecInfo.TotalDataBits(),
+		ModulesDiffer: -1,
+	}
+
+	unmasked := result.unmaskedSymbol()
+	inspection.MaskScores = unmasked.maskScores()
+
+	_, used, _ := parseSegments(result.DataCodewords, result.Version)
+	inspection.DataBits = used
+
+	// Terminator (up to 4 bits) and bit padding come first, whole bytes after that are pad bytes
+	padStart := (min(used+4, ecInfo.TotalDataBits()) + 7) / 8
+	inspection.PadBytes = len(result.DataCodewords) - padStart
+	inspection.PadPattern = true
+	for i, b := range result.DataCodewords[padStart:] {
+		if b != []byte{0xEC, 0x11}[i%2] {
+			inspection.PadPattern = false
+		}
+	}
+
+	content := string(result.Data)
+	if encoded, err := GenerateQRCodeWithOptions(content, EncodeOptions{EcLevel: result.EcLevel}); err == nil {
+		inspection.EncoderVersion = encoded.Version
+		inspection.EncoderMask = encoded.mask
+		inspection.EncoderMode = encoded.EncodingMode
+	}
+
+	sameVersion, err := GenerateQRCodeWithOptions(content, EncodeOptions{EcLevel: result.EcLevel, Version: int(result.Version)})
+	if err == nil {
+		grid := sameVersion.BitMatrix()
+
+		inspection.ModulesDiffer = 0
+		for x, column := range result.symbol().BitMatrix() {
+			for y, dark := range column {
+				if grid[x][y] != dark {
+					inspection.ModulesDiffer++
+				}
+			}
+		}
+	}
+
+	return inspection
+}
+
+// Rebuilds the symbol from the corrected codewords, before masking
+func (r *DecodeResult) unmaskedSymbol() *QRCode {
+	ecInfo := getEcInfo(r.Version, r.EcLevel)
+	symbol := New(r.Version, r.EcLevel)
+	symbol.addFunctionPatterns()
+	symbol.WriteData(getFinalMessage(r.DataCodewords, ecInfo))
+	return symbol
+}
+
+// The symbol as it should have been printed, without any damage
+func (r *DecodeResult) symbol() *QRCode {
+	symbol := r.unmaskedSymbol()
+	symbol.ApplyMask(r.Mask)
+	symbol.WriteFormatInfo()
+	symbol.WriteVersionInfo()
+	return symbol
+}
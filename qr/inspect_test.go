@@ -0,0 +1,44 @@
+package qr
+
+import "testing"
+
+func TestInspect(t *testing.T) {
+	qrCode := GenerateQRCode("HELLO WORLD 123", EC_Medium, 0, false)
+	inspections, err := InspectImage(qrCode.GenerateImage(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inspection := inspections[0]
+	if !inspection.MatchesEncoder() {
+		t.Errorf("own symbol should match the encoder (%d modules differ)", inspection.ModulesDiffer)
+	}
+	// The scores are the ones the encoder saw, so its pick has to be the best one
+	if best := bestMask(inspection.MaskScores); best != qrCode.mask {
+		t.Errorf("best mask by score is %d, encoder picked %d (%v)", best, qrCode.mask, inspection.MaskScores)
+	}
+	// 4 bits mode + 9 bits count + 11*7 + 6 bits data
+	if inspection.DataBits != 96 || inspection.CapacityBits != 128 {
+		t.Errorf("data bits %d/%d, expected 96/128", inspection.DataBits, inspection.CapacityBits)
+	}
+	if inspection.PadBytes != 3 || !inspection.PadPattern {
+		t.Errorf("pad bytes %d (standard %t), expected 3 standard", inspection.PadBytes, inspection.PadPattern)
+	}
+}
+
+func TestInspectForeignSymbol(t *testing.T) {
+	// Same content with a worse mask than ours, like another encoder might pick
+	qrCode := GenerateQRCode("HELLO WORLD 123", EC_Medium, 0, false)
+	other := qrCode.Clone()
+	other.ApplyMask(qrCode.mask)
+	other.ApplyMask(qrCode.mask ^ 1)
+	other.WriteFormatInfo()
+
+	inspections, err := InspectImage(other.GenerateImage(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inspections[0].MatchesEncoder() || inspections[0].ModulesDiffer <= 0 {
+		t.Errorf("remasked symbol should not match the encoder")
+	}
+}
@@ -0,0 +1,169 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// Logo drawn in the middle of the symbol. The modules underneath are lost,
+// so the encoder picks an EC level and version that can correct all of them.
+type Logo struct {
+	Image   image.Image
+	MaxSize float64 // Largest side of the logo as a fraction of the symbol width, 0 = 0.2
+	Padding int     // Light modules around the logo
+
+	// EC codewords per block that have to stay free for regular damage
+	// (dirt, creases...) on top of what the logo takes
+	Reserve int
+}
+
+const defaultLogoSize = 0.2
+
+// Logo placement on a symbol, in module coordinates
+type placedLogo struct {
+	img   image.Image
+	area  image.Rectangle // Padding included, every module in here is lost
+	inner image.Rectangle // Where the logo itself goes
+}
+
+// Centered, keeps the logo's aspect ratio
+func placeLogo(logo *Logo, size int) (*placedLogo, error) {
+	bounds := logo.Image.Bounds()
+	if bounds.Empty() {
+		return nil, fmt.Errorf("logo image is empty")
+	}
+
+	maxSize := logo.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultLogoSize
+	}
+
+	longest := int(maxSize * float64(size))
+	if longest < 1 {
+		return nil, fmt.Errorf("logo size %.2f is too small for a %dx%d symbol", maxSize, size, size)
+	}
+
+	w, h := longest, longest
+	if bounds.Dx() > bounds.Dy() {
+		h = max(1, (longest*bounds.Dy()+bounds.Dx()/2)/bounds.Dx())
+	} else {
+		w = max(1, (longest*bounds.Dx()+bounds.Dy()/2)/bounds.Dy())
+	}
+
+	outerW, outerH := w+2*logo.Padding, h+2*logo.Padding
+	if outerW > size || outerH > size {
+		return nil, fmt.Errorf("logo with padding doesn't fit in a %dx%d symbol", size, size)
+	}
+
+	area := centeredRect(outerW, outerH, size)
+	inner := area.Inset(logo.Padding)
+	return &placedLogo{img: logo.Image, area: area, inner: inner}, nil
+}
+
+// Tries every version from the smallest that fits, and at each version every
+// EC level from the requested one up, until the logo's damage fits in every
+// block. The first one is the smallest symbol that works.
+func generateWithLogo(input string, opts EncodeOptions) (*QRCode, error) {
+	logo := opts.Logo
+	if logo.Reserve < 0 {
+		return nil, fmt.Errorf("logo reserve can't be negative")
+	}
+
+	inner := opts
+	inner.Logo = nil
+	inner.Verify = false
+	inner.Verbose = false
+
+	versions := []int{opts.Version}
+	if opts.Version == 0 {
+		versions = versions[:0]
+		for v := 1; v <= 40; v++ {
+			versions = append(versions, v)
+		}
+	}
+
+	var lastErr error
+	for _, version := range versions {
+		for ecLevel := opts.EcLevel; ecLevel <= EC_High; ecLevel++ {
+			inner.EcLevel = ecLevel
+			inner.Version = version
+
+			qrCode, err := GenerateQRCodeWithOptions(input, inner)
+			if err != nil {
+				// Doesn't fit this version/level, the next one might
+				lastErr = err
+				continue
+			}
+
+			placed, err := placeLogo(logo, qrCode.size)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+
+			damage := qrCode.DamageFromRect(placed.area)
+			if damage.Margin() < logo.Reserve {
+				lastErr = fmt.Errorf("logo damages more codewords than version %d-%s can correct", qrCode.Version, ecLevel)
+				continue
+			}
+
+			if opts.Verbose {
+				// Once more with the details of the one that was picked
+				inner.Verbose = true
+				qrCode, _ = GenerateQRCodeWithOptions(input, inner)
+				fmt.Printf("Logo: %dx%d modules, margin %d codewords per block\n", placed.area.Dx(), placed.area.Dy(), damage.Margin())
+			}
+			qrCode.logo = placed
+
+			if opts.Verify {
+				if err := qrCode.Verify([]byte(input)); err != nil {
+					return nil, err
+				}
+			}
+			return qrCode, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no version can carry this logo: %w", lastErr)
+}
+
+// Draws the logo over an image made by GenerateImageWithOptions. The padding
+// is filled with the light color, then the logo is scaled into place
+// (nearest neighbour, alpha blended) and function modules are drawn back on top.
+func (qr *QRCode) drawLogo(img *image.RGBA, quietZone, scale int, light, dark color.RGBA) {
+	logo := qr.logo
+	bounds := logo.img.Bounds()
+	origin := func(modules int) int { return (modules + quietZone) * scale }
+
+	innerX0, innerY0 := origin(logo.inner.Min.X), origin(logo.inner.Min.Y)
+	innerW, innerH := logo.inner.Dx()*scale, logo.inner.Dy()*scale
+
+	for py := origin(logo.area.Min.Y); py < origin(logo.area.Max.Y); py++ {
+		for px := origin(logo.area.Min.X); px < origin(logo.area.Max.X); px++ {
+			mx, my := px/scale-quietZone, py/scale-quietZone
+
+			if mod := qr.getModule(mx, my); mod.Reserved {
+				c := light
+				if mod.Value == ValueBlack {
+					c = dark
+				}
+				img.SetRGBA(px, py, c)
+				continue
+			}
+
+			c := light
+			if px >= innerX0 && py >= innerY0 && px < innerX0+innerW && py < innerY0+innerH {
+				sx := bounds.Min.X + (px-innerX0)*bounds.Dx()/innerW
+				sy := bounds.Min.Y + (py-innerY0)*bounds.Dy()/innerH
+				lr, lg, lb, la := logo.img.At(sx, sy).RGBA()
+				// Premultiplied: logo + background * (1 - alpha)
+				over := func(l uint32, bg uint8) uint8 {
+					return uint8((l + uint32(bg)*0x101*(0xffff-la)/0xffff) >> 8)
+				}
+				c = color.RGBA{over(lr, light.R), over(lg, light.G), over(lb, light.B), over(la, light.A)}
+			}
+			img.SetRGBA(px, py, c)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// Worst case for a decoder: noise that looks like modules
+func testLogo(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			c := color.RGBA{200, 30, 30, 255}
+			if (x/3+y/5)%2 == 0 {
+				c = color.RGBA{0, 0, 0, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestLogo(t *testing.T) {
+	for _, input := range []string{"HELLO", "https://example.com/with/a/logo", strings.Repeat("logo ", 60)} {
+		qrCode, err := GenerateQRCodeWithOptions(input, EncodeOptions{
+			EcLevel: EC_Low,
+			Logo:    &Logo{Image: testLogo(60, 40), MaxSize: 0.25, Padding: 1},
+			Verify:  true,
+		})
+		if err != nil {
+			t.Fatalf("%q: %v", input, err)
+		}
+
+		if damage := qrCode.DamageFromRect(qrCode.logo.area); !damage.Safe() {
+			t.Errorf("%q: logo damage %+v is beyond the capacity", input, damage.Blocks)
+		}
+	}
+}
+
+func TestLogoKeepsFunctionPatterns(t *testing.T) {
+	// Version 7+ has an alignment pattern right in the middle
+	qrCode, err := GenerateQRCodeWithOptions("logo", EncodeOptions{
+		Version: 7,
+		Logo:    &Logo{Image: testLogo(10, 10), MaxSize: 0.3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := qrCode.GenerateImage(4)
+	for x := range qrCode.size {
+		for y := range qrCode.size {
+			mod := qrCode.getModule(x, y)
+			if !mod.Reserved {
+				continue
+			}
+			c := img.RGBAAt((x+4)*4+2, (y+4)*4+2)
+			if (c.R == 0) != (mod.Value == ValueBlack) {
+				t.Fatalf("function module (%d,%d) is covered", x, y)
+			}
+		}
+	}
+}
+
+func TestLogoTooLarge(t *testing.T) {
+	_, err := GenerateQRCodeWithOptions("HELLO", EncodeOptions{
+		Logo: &Logo{Image: testLogo(10, 10), MaxSize: 0.7},
+	})
+	if err == nil {
+		t.Fatal("expected a logo over 70% of the symbol to be refused")
+	}
+}
@@ -6,25 +6,38 @@ import (
 )
 
 func (qr *QRCode) ApplyBestMask() {
-	bestScore := math.MaxInt
-	bestMask := 0
+	bestMask := bestMask(qr.maskScores())
+
+	if verbose {
+		fmt.Printf("Mask: %d\n", bestMask)
+	}
 
+	qr.ApplyMask(bestMask)
+}
+
+// Penalty score of every mask, for an unmasked symbol
+func (qr *QRCode) maskScores() [8]int {
+	var scores [8]int
 	for mask := range 8 {
 		clone := qr.Clone()
 		clone.ApplyMask(mask)
-		score := clone.ScoreMask()
+		scores[mask] = clone.ScoreMask()
+	}
+	return scores
+}
+
+// Lowest score wins, ties go to the lowest mask
+func bestMask(scores [8]int) int {
+	bestScore := math.MaxInt
+	best := 0
 
+	for mask, score := range scores {
 		if score < bestScore {
 			bestScore = score
-			bestMask = mask
+			best = mask
 		}
 	}
-
-	if verbose {
-		fmt.Printf("Mask: %d\n", bestMask)
-	}
-
-	qr.ApplyMask(bestMask)
+	return best
 }
 
 func (qr *QRCode) ApplyMask(mask int) {
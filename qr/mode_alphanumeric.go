@@ -16,6 +16,9 @@ var alphaNumMap = [128]uint8{
 	'-': 41, '.': 42, '/': 43, ':': 44,
 }
 
+// Reverse of alphaNumMap
+const alphanumericChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
 func canEcodeAlphanumeric(s string) bool {
 	for _, r := range s {
 		if _, ok := alphanumericValue(r); !ok {
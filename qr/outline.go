@@ -0,0 +1,96 @@
+package qr
+
+import "image"
+
+// outlines traces the borders of the dark areas into closed polygons
+// (module corners, y pointing down). Holes come out as their own polygons
+// wound the other way, so both nonzero and even-odd filling work.
+// Consecutive points never lie on one line.
+func (qr *QRCode) outlines() [][]image.Point {
+	// Every border edge of a dark module, clockwise (dark side on the right)
+	next := map[image.Point][]image.Point{}
+	add := func(from, to image.Point) {
+		next[from] = append(next[from], to)
+	}
+
+	for y := range qr.size {
+		for x := range qr.size {
+			if !qr.At(x, y) {
+				continue
+			}
+			if !qr.At(x, y-1) {
+				add(image.Pt(x, y), image.Pt(x+1, y))
+			}
+			if !qr.At(x+1, y) {
+				add(image.Pt(x+1, y), image.Pt(x+1, y+1))
+			}
+			if !qr.At(x, y+1) {
+				add(image.Pt(x+1, y+1), image.Pt(x, y+1))
+			}
+			if !qr.At(x-1, y) {
+				add(image.Pt(x, y+1), image.Pt(x, y))
+			}
+		}
+	}
+
+	// Deterministic starting points: top to bottom, left to right
+	var polygons [][]image.Point
+	for y := 0; y <= qr.size; y++ {
+		for x := 0; x <= qr.size; x++ {
+			start := image.Pt(x, y)
+			for len(next[start]) > 0 {
+				polygons = append(polygons, simplify(walk(next, start)))
+			}
+		}
+	}
+	return polygons
+}
+
+// Follows edges from start until it's back. Where two dark modules only touch
+// at a corner there are two ways to go; turning right keeps them apart.
+func walk(next map[image.Point][]image.Point, start image.Point) []image.Point {
+	var polygon []image.Point
+	from := start
+	dir := image.Point{}
+
+	for {
+		candidates := next[from]
+		i := 0
+		if len(candidates) > 1 {
+			for j, to := range candidates {
+				d := to.Sub(from)
+				// Right turn in y-down coordinates
+				if d == (image.Point{-dir.Y, dir.X}) {
+					i = j
+				}
+			}
+		}
+
+		to := candidates[i]
+		next[from] = append(candidates[:i], candidates[i+1:]...)
+		if len(next[from]) == 0 {
+			delete(next, from)
+		}
+
+		polygon = append(polygon, from)
+		dir = to.Sub(from)
+		from = to
+		if from == start {
+			return polygon
+		}
+	}
+}
+
+// Drops the points in the middle of straight lines
+func simplify(polygon []image.Point) []image.Point {
+	n := len(polygon)
+	out := make([]image.Point, 0, n)
+	for i, p := range polygon {
+		prev, next := polygon[(i+n-1)%n], polygon[(i+1)%n]
+		if (p.X-prev.X)*(next.Y-p.Y) == (p.Y-prev.Y)*(next.X-p.X) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
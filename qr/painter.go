@@ -0,0 +1,60 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// ModulePainter draws the modules itself, for gradients, textures, icons or
+// anything else the built-in styles don't do. The raster renderer calls it
+// once for every module of the symbol, dark and light, instead of filling the
+// module. The image already has the background color (quiet zone included).
+type ModulePainter interface {
+	PaintModule(dst draw.Image, rect image.Rectangle, module SymbolModule)
+}
+
+// Lets a plain function be a ModulePainter
+type ModulePainterFunc func(dst draw.Image, rect image.Rectangle, module SymbolModule)
+
+func (f ModulePainterFunc) PaintModule(dst draw.Image, rect image.Rectangle, module SymbolModule) {
+	f(dst, rect, module)
+}
+
+// What a painter gets to know about a module
+type SymbolModule struct {
+	X, Y int // In modules, (0, 0) is the top left corner of the symbol
+	Module
+	ModuleInfo
+
+	// Foreground/Background from the options, already swapped when Inverted
+	LightColor, DarkColor color.RGBA
+	Inverted              bool
+}
+
+func (m SymbolModule) Dark() bool {
+	return m.Value == ValueBlack
+}
+
+// The color the renderer would have filled the module with
+func (m SymbolModule) Color() color.RGBA {
+	if m.Dark() {
+		return m.DarkColor
+	}
+	return m.LightColor
+}
+
+func (qr *QRCode) paint(img *image.RGBA, painter ModulePainter, quietZone, scale int, opts ImageOptions) {
+	light, dark := opts.colors()
+	roles := qr.RoleMap()
+	for y := range qr.size {
+		for x := range qr.size {
+			origin := image.Pt((x+quietZone)*scale, (y+quietZone)*scale)
+			rect := image.Rectangle{origin, origin.Add(image.Pt(scale, scale))}
+			painter.PaintModule(img, rect, SymbolModule{
+				X: x, Y: y, Module: *qr.getModule(x, y), ModuleInfo: roles[x][y],
+				LightColor: light, DarkColor: dark, Inverted: opts.inverted(),
+			})
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestModulePainter(t *testing.T) {
+	content := "https://example.com/painter"
+	qrCode := GenerateQRCode(content, EC_Medium, 0, false)
+
+	red := color.RGBA{0xc6, 0x28, 0x28, 0xff}
+	calls, finders := 0, 0
+	painter := ModulePainterFunc(func(dst draw.Image, rect image.Rectangle, m SymbolModule) {
+		calls++
+		if m.Role == Role_Finder && m.Dark() {
+			finders++
+		}
+		if !m.Dark() {
+			return
+		}
+		c := color.Color(color.Black)
+		if m.Role == Role_Data || m.Role == Role_EC {
+			c = red
+		}
+		draw.Draw(dst, rect, image.NewUniform(c), image.Point{}, draw.Src)
+	})
+
+	img := qrCode.GenerateImageWithOptions(ImageOptions{Scale: 4, Painter: painter})
+
+	if size := qrCode.Size(); calls != size*size {
+		t.Errorf("painter called %d times, expected once per module (%d)", calls, size*size)
+	}
+	if finders != 3*(24+9) {
+		t.Errorf("painter saw %d dark finder modules, expected %d", finders, 3*(24+9))
+	}
+	if err := VerifyImage(img, []byte(content)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestModulePainterColors(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/painter", EC_Medium, 0, false)
+	navy := color.RGBA{0x1a, 0x23, 0x7e, 0xff}
+
+	painter := ModulePainterFunc(func(dst draw.Image, rect image.Rectangle, m SymbolModule) {
+		draw.Draw(dst, rect, image.NewUniform(m.Color()), image.Point{}, draw.Src)
+	})
+
+	// Painting every module its own color has to match the plain render
+	for _, opts := range []ImageOptions{
+		{Scale: 2, Foreground: navy},
+		{Scale: 2, Foreground: navy, Invert: true},
+	} {
+		plain := qrCode.GenerateImageWithOptions(opts)
+		opts.Painter = painter
+		painted := qrCode.GenerateImageWithOptions(opts)
+		if string(plain.Pix) != string(painted.Pix) {
+			t.Errorf("invert %v: painted image differs from the plain one", opts.Invert)
+		}
+	}
+}
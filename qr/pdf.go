@@ -0,0 +1,120 @@
+package qr
+
+import (
+	"aboutblank/qr-code/pdf"
+	"fmt"
+	"io"
+)
+
+type PDFOptions struct {
+	ModuleSize float64 // Points per module, 0 = 0.5mm. pdf.MM converts from millimeters
+	QuietZone  *int    // Light modules around the symbol, nil = the standard 4, QuietZone(0) = none
+	Bleed      float64 // Points the background extends past the trim box
+	CropMarks  bool    // Corner marks outside the bleed, the page grows to fit them
+	Invert     bool    // Light modules on a dark background
+}
+
+const (
+	defaultPDFModuleMM = 0.5
+	cropMarkLength     = 12 // pt
+	cropMarkOffset     = 3  // pt, gap between the bleed and the marks
+	cropMarkWidth      = 0.25
+)
+
+// WritePDF writes the symbol as a single page PDF
+func (qr *QRCode) WritePDF(w io.Writer, opts PDFOptions) error {
+	return WritePDF(w, []*QRCode{qr}, opts)
+}
+
+// WritePDF writes every symbol on its own page. The trim box is exactly the
+// symbol with its quiet zone; dark modules are filled rectangles, one per
+// horizontal run, all in a single path. Logos are not drawn.
+func WritePDF(w io.Writer, codes []*QRCode, opts PDFOptions) error {
+	if len(codes) == 0 {
+		return fmt.Errorf("no QR codes to write")
+	}
+
+	moduleSize := opts.ModuleSize
+	if moduleSize <= 0 {
+		moduleSize = pdf.MM(defaultPDFModuleMM)
+	}
+	quietZone := quietZoneOf(opts.QuietZone)
+	bleed := max(opts.Bleed, 0)
+
+	margin := bleed
+	if opts.CropMarks {
+		margin += cropMarkOffset + cropMarkLength
+	}
+
+	doc := pdf.New()
+	for _, qr := range codes {
+		side := float64(qr.size+2*quietZone) * moduleSize
+		page := doc.AddPage(side+2*margin, side+2*margin)
+		trim := pdf.Rect{X: margin, Y: margin, W: side, H: side}
+		page.TrimBox = trim
+		page.BleedBox = trim.Outset(bleed)
+
+		light, dark := 1.0, 0.0
+		if opts.Invert {
+			light, dark = dark, light
+		}
+
+		page.SetFillRGB(light, light, light)
+		page.Rect(page.BleedBox)
+		page.Fill()
+
+		// PDF's y axis points up, row 0 is at the top
+		page.SetFillRGB(dark, dark, dark)
+		for y := range qr.size {
+			for x := 0; x < qr.size; x++ {
+				if !qr.At(x, y) {
+					continue
+				}
+				start := x
+				for x+1 < qr.size && qr.At(x+1, y) {
+					x++
+				}
+				page.Rect(pdf.Rect{
+					X: trim.X + float64(start+quietZone)*moduleSize,
+					Y: trim.Y + side - float64(y+quietZone+1)*moduleSize,
+					W: float64(x-start+1) * moduleSize,
+					H: moduleSize,
+				})
+			}
+		}
+		page.Fill()
+
+		if opts.CropMarks {
+			drawCropMarks(page, trim, bleed)
+		}
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// Two short lines at every corner, in line with the trim box edges
+func drawCropMarks(page *pdf.Page, trim pdf.Rect, bleed float64) {
+	page.SetStrokeRGB(0, 0, 0)
+	page.SetLineWidth(cropMarkWidth)
+
+	gap := bleed + cropMarkOffset
+	for _, x := range []float64{trim.X, trim.X + trim.W} {
+		for _, y := range []float64{trim.Y, trim.Y + trim.H} {
+			// Away from the symbol
+			dx, dy := -1.0, -1.0
+			if x > trim.X {
+				dx = 1
+			}
+			if y > trim.Y {
+				dy = 1
+			}
+
+			page.MoveTo(x+dx*gap, y)
+			page.LineTo(x+dx*(gap+cropMarkLength), y)
+			page.MoveTo(x, y+dy*gap)
+			page.LineTo(x, y+dy*(gap+cropMarkLength))
+		}
+	}
+	page.Stroke()
+}
@@ -0,0 +1,70 @@
+package qr
+
+import (
+	"aboutblank/qr-code/pdf"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWritePDF(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/pdf", EC_Medium, 0, false)
+
+	var buf bytes.Buffer
+	if err := qrCode.WritePDF(&buf, PDFOptions{ModuleSize: 2, QuietZone: QuietZone(0)}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	side := strconv.Itoa(qrCode.Size() * 2)
+	if !strings.Contains(out, "/MediaBox [0 0 "+side+" "+side+"]") {
+		t.Errorf("media box should be exactly the symbol (%s pt)", side)
+	}
+
+	// Paint the module runs back into a grid, skipping the background
+	grid := make([][]bool, qrCode.Size())
+	for x := range grid {
+		grid[x] = make([]bool, qrCode.Size())
+	}
+	rects := regexp.MustCompile(`([\d.]+) ([\d.]+) ([\d.]+) 2 re`).FindAllStringSubmatch(out, -1)
+	for _, r := range rects {
+		x, _ := strconv.ParseFloat(r[1], 64)
+		y, _ := strconv.ParseFloat(r[2], 64)
+		w, _ := strconv.ParseFloat(r[3], 64)
+		row := qrCode.Size() - 1 - int(y)/2
+		for i := range int(w) / 2 {
+			grid[int(x)/2+i][row] = true
+		}
+	}
+
+	matrix := qrCode.BitMatrix()
+	for x := range matrix {
+		for y := range matrix {
+			if grid[x][y] != matrix[x][y] {
+				t.Fatalf("module (%d,%d) differs", x, y)
+			}
+		}
+	}
+}
+
+func TestWritePDFPages(t *testing.T) {
+	codes := []*QRCode{
+		GenerateQRCode("page 1", EC_Low, 0, false),
+		GenerateQRCode("page 2", EC_Low, 0, false),
+	}
+
+	var buf bytes.Buffer
+	if err := WritePDF(&buf, codes, PDFOptions{ModuleSize: pdf.MM(0.5), Bleed: pdf.MM(3), CropMarks: true}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	if !strings.Contains(out, "/Count 2") {
+		t.Error("expected 2 pages")
+	}
+	if !strings.Contains(out, "/BleedBox") || strings.Count(out, "\nS\n") != 2 {
+		t.Error("expected a bleed box and crop marks on every page")
+	}
+}
@@ -0,0 +1,72 @@
+package qr
+
+// Projective transform between two quadrilaterals.
+// [x', y', w]ᵀ = m · [x, y, 1]ᵀ
+type perspective [3][3]float64
+
+// Maps the unit square (0,0), (1,0), (1,1), (0,1) onto the given corners.
+// See Heckbert, "Fundamentals of Texture Mapping and Image Warping".
+func squareToQuad(p0, p1, p2, p3 Point) perspective {
+	dx3 := p0.X - p1.X + p2.X - p3.X
+	dy3 := p0.Y - p1.Y + p2.Y - p3.Y
+
+	if dx3 == 0 && dy3 == 0 {
+		// Affine (parallelogram)
+		return perspective{
+			{p1.X - p0.X, p2.X - p1.X, p0.X},
+			{p1.Y - p0.Y, p2.Y - p1.Y, p0.Y},
+			{0, 0, 1},
+		}
+	}
+
+	dx1 := p1.X - p2.X
+	dx2 := p3.X - p2.X
+	dy1 := p1.Y - p2.Y
+	dy2 := p3.Y - p2.Y
+	denom := dx1*dy2 - dx2*dy1
+	g := (dx3*dy2 - dx2*dy3) / denom
+	h := (dx1*dy3 - dx3*dy1) / denom
+
+	return perspective{
+		{p1.X - p0.X + g*p1.X, p3.X - p0.X + h*p3.X, p0.X},
+		{p1.Y - p0.Y + g*p1.Y, p3.Y - p0.Y + h*p3.Y, p0.Y},
+		{g, h, 1},
+	}
+}
+
+// Maps the quadrilateral src onto dst (both in corner order 0..3)
+func quadToQuad(src, dst [4]Point) perspective {
+	toSquare := squareToQuad(src[0], src[1], src[2], src[3]).adjugate()
+	fromSquare := squareToQuad(dst[0], dst[1], dst[2], dst[3])
+	return fromSquare.times(toSquare)
+}
+
+// The adjugate is the inverse up to a scale factor, which is all
+// a projective transform needs.
+func (m perspective) adjugate() perspective {
+	return perspective{
+		{m[1][1]*m[2][2] - m[1][2]*m[2][1], m[0][2]*m[2][1] - m[0][1]*m[2][2], m[0][1]*m[1][2] - m[0][2]*m[1][1]},
+		{m[1][2]*m[2][0] - m[1][0]*m[2][2], m[0][0]*m[2][2] - m[0][2]*m[2][0], m[0][2]*m[1][0] - m[0][0]*m[1][2]},
+		{m[1][0]*m[2][1] - m[1][1]*m[2][0], m[0][1]*m[2][0] - m[0][0]*m[2][1], m[0][0]*m[1][1] - m[0][1]*m[1][0]},
+	}
+}
+
+func (m perspective) times(o perspective) perspective {
+	var out perspective
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				out[i][j] += m[i][k] * o[k][j]
+			}
+		}
+	}
+	return out
+}
+
+func (m perspective) transform(x, y float64) Point {
+	w := m[2][0]*x + m[2][1]*y + m[2][2]
+	return Point{
+		X: (m[0][0]*x + m[0][1]*y + m[0][2]) / w,
+		Y: (m[1][0]*x + m[1][1]*y + m[1][2]) / w,
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"aboutblank/qr-code/bitreader"
 	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
 )
 
 type Version uint8
@@ -15,6 +17,7 @@ type QRCode struct {
 
 	moduleMatrix [][]Module
 	mask         int
+	logo         *placedLogo // Drawn over the modules when rendering
 
 	size             int
 	formatPositions  [30][2]int
@@ -89,10 +92,41 @@ func (qr *QRCode) Clone() *QRCode {
 		copy(newMatrix[i], qr.moduleMatrix[i])
 	}
 
-	return &QRCode{
-		Version:      qr.Version,
-		moduleMatrix: newMatrix,
+	clone := *qr
+	clone.moduleMatrix = newMatrix
+	return &clone
+}
+
+// Width (and height) in modules
+func (qr *QRCode) Size() int {
+	return qr.size
+}
+
+// Whether the module at column x, row y is dark. Outside the symbol is the
+// (light) quiet zone.
+func (qr *QRCode) At(x, y int) bool {
+	if x < 0 || y < 0 || x >= qr.size || y >= qr.size {
+		return false
+	}
+	return qr.moduleMatrix[x][y].Value == ValueBlack
+}
+
+// Mask pattern (0-7) the symbol was encoded with, -1 if it isn't masked yet
+func (qr *QRCode) Mask() int {
+	return qr.mask
+}
+
+// BitMatrix returns a copy of the module matrix as dark (true) / light modules,
+// indexed [x][y] like DecodeGrid expects.
+func (qr *QRCode) BitMatrix() [][]bool {
+	grid := make([][]bool, qr.size)
+	for x := range grid {
+		grid[x] = make([]bool, qr.size)
+		for y := range grid[x] {
+			grid[x][y] = qr.moduleMatrix[x][y].Value == ValueBlack
+		}
 	}
+	return grid
 }
 
 func (qr *QRCode) getModule(x, y int) *Module {
@@ -105,11 +139,7 @@ func (qr *QRCode) setModule(x, y int, value ModuleValue, reserved bool) {
 }
 
 func (qr *QRCode) ApplyFinalMessage(data []byte) {
-	qr.AddFinderPatternsAndSeparators()
-	qr.AddAlignmentPatterns()
-	qr.AddTimingPatterns()
-	qr.AddDarkModule()
-	qr.ReserveFormatAndVersionModules()
+	qr.addFunctionPatterns()
 
 	qr.WriteData(data)
 	qr.ApplyBestMask()
@@ -118,6 +148,16 @@ func (qr *QRCode) ApplyFinalMessage(data []byte) {
 	qr.WriteVersionInfo()
 }
 
+// Everything that is not data: finders, alignment, timing, dark module
+// and the (still empty) format/version areas.
+func (qr *QRCode) addFunctionPatterns() {
+	qr.AddFinderPatternsAndSeparators()
+	qr.AddAlignmentPatterns()
+	qr.AddTimingPatterns()
+	qr.AddDarkModule()
+	qr.ReserveFormatAndVersionModules()
+}
+
 func (qr *QRCode) AddFinderPatternsAndSeparators() {
 	size := qr.size
 
@@ -334,27 +374,139 @@ func (qr *QRCode) dataPositions() [][2]int {
 	return positions
 }
 
+type ImageOptions struct {
+	Scale     int  // Pixels per module
+	QuietZone *int // Light modules around the symbol, nil = the standard 4, QuietZone(0) = none
+	Invert    bool // Light modules on a dark background (quiet zone included), swaps the colors
+
+	// nil = black on white. color.Transparent as the background for overlays.
+	// GenerateImageWithOptions renders any pair as asked, GenerateCheckedImage
+	// refuses the ones that won't scan.
+	Foreground color.Color
+	Background color.Color
+
+	// Target width/height in pixels, replaces Scale. Picks the largest whole
+	// number of pixels per module that fits, so the image can come out smaller
+	// unless Center or Smooth is set. A Size under 1 pixel per module always
+	// scales like Smooth, nothing else fits.
+	Size   int
+	Center bool // Exactly Size pixels, the symbol centered on extra background
+	Smooth bool // Exactly Size pixels with a fractional scale, edges anti-aliased
+
+	ModuleStyle ModuleStyle
+	FinderStyle FinderStyle
+	Painter     ModulePainter // Draws the modules instead, the styles and Fill are ignored
+
+	// Gradient or image for the dark modules instead of Foreground, Invert is
+	// ignored with it. GenerateCheckedImage checks it too.
+	Fill Fill
+}
+
+// Colors of the light and dark modules
+func (opts ImageOptions) colors() (light, dark color.RGBA) {
+	light, dark = rgba(opts.Background, color.White), rgba(opts.Foreground, color.Black)
+	if opts.inverted() {
+		light, dark = dark, light
+	}
+	return light, dark
+}
+
+// A Fill ignores Invert, unless a Painter is ignoring the Fill
+func (opts ImageOptions) inverted() bool {
+	return opts.Invert && (opts.Fill == nil || opts.Painter != nil)
+}
+
+func rgba(c, fallback color.Color) color.RGBA {
+	if c == nil {
+		c = fallback
+	}
+	return color.RGBAModel.Convert(c).(color.RGBA)
+}
+
 func (qr *QRCode) GenerateImage(scale int) *image.RGBA {
-	size := qr.size
-	padding := 4 // quiet zone (light modules)
-	gridSize := size + (padding * 2)
+	return qr.GenerateImageWithOptions(ImageOptions{Scale: scale})
+}
+
+// GenerateImageWithOptions renders the symbol as opts describe. The colors
+// are not checked, use GenerateCheckedImage for that.
+func (qr *QRCode) GenerateImageWithOptions(opts ImageOptions) *image.RGBA {
+	quietZone := quietZoneOf(opts.QuietZone)
+	gridSize := qr.size + 2*quietZone
+
+	if opts.Size <= 0 {
+		return qr.render(opts, quietZone, opts.Scale)
+	}
+
+	if qr.smooth(opts) {
+		// Whole modules at least as big as the target pixels, then averaged down
+		scale := (opts.Size + gridSize - 1) / gridSize
+		return resample(qr.render(opts, quietZone, scale), opts.Size)
+	}
+
+	img := qr.render(opts, quietZone, opts.Size/gridSize)
+	if opts.Center && img.Bounds().Dx() < opts.Size {
+		light, _ := opts.colors()
+		canvas := image.NewRGBA(image.Rect(0, 0, opts.Size, opts.Size))
+		draw.Draw(canvas, canvas.Bounds(), image.NewUniform(light), image.Point{}, draw.Src)
+		offset := (opts.Size - img.Bounds().Dx()) / 2
+		draw.Draw(canvas, img.Bounds().Add(image.Pt(offset, offset)), img, image.Point{}, draw.Src)
+		return canvas
+	}
+	return img
+}
+
+// Size is reached with a fractional scale, asked for or because not even a
+// pixel per module fits
+func (qr *QRCode) smooth(opts ImageOptions) bool {
+	return opts.Size > 0 && (opts.Smooth || opts.Size < qr.size+2*quietZoneOf(opts.QuietZone))
+}
+
+func (qr *QRCode) render(opts ImageOptions, padding, scale int) *image.RGBA {
+	gridSize := qr.size + (padding * 2)
+
+	light, dark := opts.colors()
 
 	w, h := gridSize*scale, gridSize*scale
 	img := image.NewRGBA(image.Rect(0, 0, w, h))
 	pix := img.Pix
-	stride := img.Stride
 
-	// make everything white
-	for i := range pix {
-		pix[i] = 255
+	// make everything light (alpha included)
+	for i := 0; i < len(pix); i += 4 {
+		pix[i], pix[i+1], pix[i+2], pix[i+3] = light.R, light.G, light.B, light.A
+	}
+
+	switch {
+	case opts.Painter != nil:
+		qr.paint(img, opts.Painter, padding, scale, opts)
+	case opts.Fill != nil:
+		// The module shapes go into a coverage mask first, the fill colors them
+		mask := image.NewRGBA(img.Bounds())
+		qr.drawModules(mask, opts, padding, scale, color.RGBA{}, color.RGBA{A: 255})
+		qr.drawFill(img, mask, opts.Fill, padding, scale, light)
+	default:
+		qr.drawModules(img, opts, padding, scale, light, dark)
 	}
 
-	for x := range size {
-		for y := range size {
-			c := byte(255)
+	if qr.logo != nil {
+		qr.drawLogo(img, padding, scale, light, dark)
+	}
+
+	return img
+}
 
-			if qr.moduleMatrix[x][y].Value == ValueBlack {
-				c = 0
+// Dark modules on top of the light background, plain or styled
+func (qr *QRCode) drawModules(img *image.RGBA, opts ImageOptions, padding, scale int, light, dark color.RGBA) {
+	if !squareStyle(opts.ModuleStyle, opts.FinderStyle) {
+		qr.style(opts.ModuleStyle, opts.FinderStyle).draw(img, padding, scale, light, dark)
+		return
+	}
+
+	pix := img.Pix
+	stride := img.Stride
+	for x := range qr.size {
+		for y := range qr.size {
+			if qr.moduleMatrix[x][y].Value != ValueBlack {
+				continue
 			}
 
 			drawX := (x + padding) * scale
@@ -363,14 +515,12 @@ func (qr *QRCode) GenerateImage(scale int) *image.RGBA {
 				rowStart := (drawY+dy)*stride + drawX*4
 				for dx := range scale {
 					offset := rowStart + dx*4
-					pix[offset+0] = c
-					pix[offset+1] = c
-					pix[offset+2] = c
-					pix[offset+3] = 255
+					pix[offset+0] = dark.R
+					pix[offset+1] = dark.G
+					pix[offset+2] = dark.B
+					pix[offset+3] = dark.A
 				}
 			}
 		}
 	}
-
-	return img
 }
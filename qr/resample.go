@@ -0,0 +1,55 @@
+package qr
+
+import "image"
+
+// Weight of one source pixel in a destination pixel
+type tap struct {
+	src    int
+	weight float64
+}
+
+// resample scales a square image down to size x size by area averaging: every
+// destination pixel is the mean of the source area it covers, partly covered
+// source pixels count by how much of them is covered. Since the source has
+// sharp module edges, this is exact anti-aliasing.
+func resample(src *image.RGBA, size int) *image.RGBA {
+	taps := areaTaps(src.Bounds().Dx(), size)
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	for dy := range size {
+		for dx := range size {
+			var sum [4]float64
+			for _, ty := range taps[dy] {
+				for _, tx := range taps[dx] {
+					w := tx.weight * ty.weight
+					o := src.PixOffset(tx.src, ty.src)
+					for c := range 4 {
+						sum[c] += w * float64(src.Pix[o+c])
+					}
+				}
+			}
+			o := dst.PixOffset(dx, dy)
+			for c := range 4 {
+				dst.Pix[o+c] = uint8(min(sum[c]+0.5, 255))
+			}
+		}
+	}
+	return dst
+}
+
+// For every destination pixel along one axis, the source pixels it covers
+// and how much of them, weights summing to 1
+func areaTaps(srcSize, dstSize int) [][]tap {
+	ratio := float64(srcSize) / float64(dstSize)
+	taps := make([][]tap, dstSize)
+	for d := range taps {
+		start, end := float64(d)*ratio, float64(d+1)*ratio
+		for s := int(start); s < srcSize && float64(s) < end; s++ {
+			overlap := min(end, float64(s+1)) - max(start, float64(s))
+			if overlap > 0 {
+				taps[d] = append(taps[d], tap{s, overlap / ratio})
+			}
+		}
+	}
+	return taps
+}
@@ -0,0 +1,135 @@
+package qr
+
+// What a module is part of
+type ModuleRole uint8
+
+const (
+	Role_Finder ModuleRole = iota
+	Role_Separator
+	Role_Timing
+	Role_Alignment
+	Role_Format
+	Role_Version
+	Role_DarkModule
+	Role_Data
+	Role_EC
+	Role_Remainder // Leftover bits after the last codeword, always 0 before masking
+)
+
+func (role ModuleRole) String() string {
+	switch role {
+	case Role_Finder:
+		return "Finder"
+	case Role_Separator:
+		return "Separator"
+	case Role_Timing:
+		return "Timing"
+	case Role_Alignment:
+		return "Alignment"
+	case Role_Format:
+		return "Format"
+	case Role_Version:
+		return "Version"
+	case Role_DarkModule:
+		return "DarkModule"
+	case Role_Data:
+		return "Data"
+	case Role_EC:
+		return "EC"
+	case Role_Remainder:
+		return "Remainder"
+	}
+	return "INVALID"
+}
+
+// Function patterns and format/version info, everything the encoder reserves
+func (role ModuleRole) IsFunction() bool {
+	return role != Role_Data && role != Role_EC && role != Role_Remainder
+}
+
+type ModuleInfo struct {
+	Role ModuleRole
+
+	// Only for Data and EC modules, -1 otherwise
+	Block    int // RS block
+	Codeword int // Index inside the block (data codewords first, then EC)
+	Bit      int // Bit of the codeword, 7 = most significant (placed first)
+}
+
+// RoleMap tells for every module (indexed [x][y] like the module matrix) what
+// it belongs to, and for data/EC modules which bit of which codeword it holds.
+func (qr *QRCode) RoleMap() [][]ModuleInfo {
+	size := qr.size
+	roles := make([][]ModuleInfo, size)
+	for x := range roles {
+		roles[x] = make([]ModuleInfo, size)
+	}
+
+	set := func(x, y int, role ModuleRole) {
+		roles[x][y] = ModuleInfo{Role: role, Block: -1, Codeword: -1, Bit: -1}
+	}
+
+	// Timing first, the patterns below overwrite the parts it doesn't own
+	for i := range size {
+		set(6, i, Role_Timing)
+		set(i, 6, Role_Timing)
+	}
+
+	for _, corner := range [3][2]int{{0, 0}, {size - 8, 0}, {0, size - 8}} {
+		for x := range 8 {
+			for y := range 8 {
+				set(corner[0]+x, corner[1]+y, Role_Separator)
+			}
+		}
+	}
+	for _, corner := range [3][2]int{{0, 0}, {size - 7, 0}, {0, size - 7}} {
+		for x := range 7 {
+			for y := range 7 {
+				set(corner[0]+x, corner[1]+y, Role_Finder)
+			}
+		}
+	}
+
+	for _, origin := range qr.alignmentOrigins() {
+		for x := range 5 {
+			for y := range 5 {
+				set(origin[0]+x, origin[1]+y, Role_Alignment)
+			}
+		}
+	}
+
+	for _, p := range qr.formatPositions {
+		set(p[0], p[1], Role_Format)
+	}
+	if qr.Version >= 7 {
+		for _, p := range qr.versionPositions {
+			set(p[0], p[1], Role_Version)
+		}
+	}
+	set(8, 4*int(qr.Version)+9, Role_DarkModule)
+
+	ecInfo := getEcInfo(qr.Version, qr.EcLevel)
+	order := interleaveOrder(ecInfo)
+	dataLen := func(block int) int {
+		if block < ecInfo.Group1.Blocks {
+			return ecInfo.Group1.DataCodewords
+		}
+		return ecInfo.Group2.DataCodewords
+	}
+
+	for i, p := range newTemplate(qr.Version).dataPositions() {
+		if i/8 >= len(order) {
+			set(p[0], p[1], Role_Remainder)
+			continue
+		}
+
+		block, index := order[i/8][0], order[i/8][1]
+		role := Role_Data
+		if index >= dataLen(block) {
+			role = Role_EC
+		}
+		roles[p[0]][p[1]] = ModuleInfo{Role: role, Block: block, Codeword: index, Bit: 7 - i%8}
+	}
+
+	return roles
+}
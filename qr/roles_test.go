@@ -0,0 +1,67 @@
+package qr
+
+import "testing"
+
+func TestRoleMapCounts(t *testing.T) {
+	cases := []struct {
+		version   Version
+		counts    map[ModuleRole]int
+		remainder int
+	}{
+		{1, map[ModuleRole]int{Role_Finder: 147, Role_Separator: 45, Role_Timing: 10, Role_Format: 30, Role_DarkModule: 1, Role_Data: 128, Role_EC: 80}, 0},
+		{2, map[ModuleRole]int{Role_Alignment: 25, Role_Data: 28 * 8, Role_EC: 16 * 8}, 7},
+		{7, map[ModuleRole]int{Role_Alignment: 6 * 25, Role_Version: 36}, 0},
+	}
+
+	for _, c := range cases {
+		qrCode := New(c.version, EC_Medium)
+		counts := map[ModuleRole]int{}
+		for _, column := range qrCode.RoleMap() {
+			for _, info := range column {
+				counts[info.Role]++
+			}
+		}
+
+		for role, expected := range c.counts {
+			if counts[role] != expected {
+				t.Errorf("version %d: %d %s modules, expected %d", c.version, counts[role], role, expected)
+			}
+		}
+		if counts[Role_Remainder] != c.remainder {
+			t.Errorf("version %d: %d remainder modules, expected %d", c.version, counts[Role_Remainder], c.remainder)
+		}
+	}
+}
+
+// Reading the codewords back through the role map gives the RS blocks
+func TestRoleMapCodewords(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/roles", EC_Quartile, 5, false)
+	ecInfo := getEcInfo(qrCode.Version, qrCode.EcLevel)
+	grid := qrCode.BitMatrix()
+	expected := deinterleave(newTemplate(qrCode.Version).readCodewords(grid, qrCode.mask, ecInfo.TotalCodewords()), ecInfo)
+
+	blocks := make([][]byte, len(expected))
+	for i := range blocks {
+		blocks[i] = make([]byte, len(expected[i]))
+	}
+
+	for x, column := range qrCode.RoleMap() {
+		for y, info := range column {
+			if info.Role.IsFunction() != qrCode.getModule(x, y).Reserved {
+				t.Fatalf("(%d,%d) is %s but reserved=%t", x, y, info.Role, qrCode.getModule(x, y).Reserved)
+			}
+			if info.Role != Role_Data && info.Role != Role_EC {
+				continue
+			}
+			if grid[x][y] != maskApplies(qrCode.mask, x, y) {
+				blocks[info.Block][info.Codeword] |= 1 << info.Bit
+			}
+		}
+	}
+
+	for i := range blocks {
+		if string(blocks[i]) != string(expected[i]) {
+			t.Errorf("block %d: %v, expected %v", i, blocks[i], expected[i])
+		}
+	}
+}
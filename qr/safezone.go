@@ -0,0 +1,141 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// Damage to a single Reed-Solomon block
+type BlockDamage struct {
+	Damaged  int // Codewords with at least one covered module
+	Capacity int // Codewords the block can correct as unknown errors
+	Margin   int // Capacity - Damaged, negative means the block is lost
+}
+
+type DamageReport struct {
+	Blocks []BlockDamage
+
+	// Covered modules that aren't data: finders, timing, alignment, format
+	// and version info. They don't count against any block, but scanners
+	// need them, so they have to stay visible (drawn on top of a logo).
+	FunctionModules int
+}
+
+// Smallest margin of any block
+func (d DamageReport) Margin() int {
+	margin := math.MaxInt
+	for _, b := range d.Blocks {
+		margin = min(margin, b.Margin)
+	}
+	return margin
+}
+
+// Every block can still correct its damage
+func (d DamageReport) Safe() bool {
+	return d.Margin() >= 0
+}
+
+// DamageFromMask works out what covering the modules in mask (indexed [x][y]
+// like the module matrix, true = covered) does to each RS block. A codeword is
+// damaged as soon as one of its modules is covered. The mask has to be the
+// size of the symbol.
+func (qr *QRCode) DamageFromMask(mask [][]bool) (DamageReport, error) {
+	if len(mask) != qr.size {
+		return DamageReport{}, fmt.Errorf("mask has %d columns, the symbol %d", len(mask), qr.size)
+	}
+	for _, column := range mask {
+		if len(column) != qr.size {
+			return DamageReport{}, fmt.Errorf("mask is not %dx%d like the symbol", qr.size, qr.size)
+		}
+	}
+	return qr.damage(mask), nil
+}
+
+// DamageFromMask for masks that are known to fit
+func (qr *QRCode) damage(mask [][]bool) DamageReport {
+	ecInfo := getEcInfo(qr.Version, qr.EcLevel)
+	capacity := correctionCapacity(qr.Version, qr.EcLevel)
+
+	var report DamageReport
+	for _, codewords := range qr.erasedCodewords(mask, ecInfo) {
+		report.Blocks = append(report.Blocks, BlockDamage{
+			Damaged:  len(codewords),
+			Capacity: capacity,
+			Margin:   capacity - len(codewords),
+		})
+	}
+
+	for x := range qr.size {
+		for y := range qr.size {
+			if mask[x][y] && qr.getModule(x, y).Reserved {
+				report.FunctionModules++
+			}
+		}
+	}
+	return report
+}
+
+// DamageFromRect is DamageFromMask for a rectangle in module coordinates
+func (qr *QRCode) DamageFromRect(r image.Rectangle) DamageReport {
+	return qr.damage(qr.maskOf(func(x, y int) bool {
+		return image.Pt(x, y).In(r)
+	}))
+}
+
+// LargestCenteredSquare returns the side (in modules) of the largest centered
+// square that leaves at least `margin` codewords of correction capacity in
+// every block, 0 if not even a single module fits.
+// Function modules inside the square are expected to stay visible.
+func (qr *QRCode) LargestCenteredSquare(margin int) int {
+	best := 0
+	for side := 1; side <= qr.size; side++ {
+		if qr.DamageFromRect(centeredRect(side, side, qr.size)).Margin() < margin {
+			break
+		}
+		best = side
+	}
+	return best
+}
+
+// LargestCenteredCircle is LargestCenteredSquare for a circle. Returns the
+// diameter in modules. Modules the circle only partly covers count as covered.
+func (qr *QRCode) LargestCenteredCircle(margin int) int {
+	best := 0
+	for diameter := 1; diameter <= qr.size; diameter++ {
+		if qr.damage(qr.circleMask(float64(diameter))).Margin() < margin {
+			break
+		}
+		best = diameter
+	}
+	return best
+}
+
+// Same centering as the logo placement
+func centeredRect(w, h, size int) image.Rectangle {
+	x0, y0 := (size-w)/2, (size-h)/2
+	return image.Rect(x0, y0, x0+w, y0+h)
+}
+
+// Modules that overlap a circle around the symbol's center
+func (qr *QRCode) circleMask(diameter float64) [][]bool {
+	center := float64(qr.size) / 2
+	radius := diameter / 2
+	return qr.maskOf(func(x, y int) bool {
+		// Closest point of the module to the center
+		cx := min(max(center, float64(x)), float64(x+1))
+		cy := min(max(center, float64(y)), float64(y+1))
+		return math.Hypot(cx-center, cy-center) < radius
+	})
+}
+
+func (qr *QRCode) maskOf(covered func(x, y int) bool) [][]bool {
+	mask := make([][]bool, qr.size)
+	for x := range mask {
+		mask[x] = make([]bool, qr.size)
+		for y := range mask[x] {
+			mask[x][y] = covered(x, y)
+		}
+	}
+	return mask
+}
@@ -0,0 +1,91 @@
+package qr
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestDamageFromRect(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/safe-zone", EC_Quartile, 0, false)
+	capacity := correctionCapacity(qrCode.Version, qrCode.EcLevel)
+
+	empty := qrCode.DamageFromRect(image.Rectangle{})
+	if empty.Margin() != capacity || empty.FunctionModules != 0 {
+		t.Errorf("nothing covered: margin %d (expected %d), %d function modules", empty.Margin(), capacity, empty.FunctionModules)
+	}
+
+	// The top-left finder and its separator, no data in there
+	finder := qrCode.DamageFromRect(image.Rect(0, 0, 8, 8))
+	if finder.Margin() != capacity || finder.FunctionModules != 64 {
+		t.Errorf("finder covered: margin %d, %d function modules", finder.Margin(), finder.FunctionModules)
+	}
+
+	all := qrCode.DamageFromRect(image.Rect(0, 0, qrCode.size, qrCode.size))
+	if all.Safe() {
+		t.Error("covering everything can't be safe")
+	}
+}
+
+func TestDamageFromMaskSize(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/safe-zone", EC_Quartile, 0, false)
+
+	for _, mask := range [][][]bool{nil, {{true}}, qrCode.maskOf(func(x, y int) bool { return true })[1:]} {
+		if _, err := qrCode.DamageFromMask(mask); err == nil {
+			t.Errorf("expected an error for a %d column mask", len(mask))
+		}
+	}
+
+	// Short columns
+	mask := qrCode.maskOf(func(x, y int) bool { return false })
+	mask[3] = mask[3][:5]
+	if _, err := qrCode.DamageFromMask(mask); err == nil {
+		t.Error("expected an error for a ragged mask")
+	}
+
+	mask = qrCode.maskOf(func(x, y int) bool { return x < 8 && y < 8 })
+	damage, err := qrCode.DamageFromMask(mask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rect := qrCode.DamageFromRect(image.Rect(0, 0, 8, 8)); !reflect.DeepEqual(damage, rect) {
+		t.Errorf("mask gives %+v, rect %+v", damage, rect)
+	}
+}
+
+func TestLargestCenteredShapes(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/safe-zone", EC_High, 0, false)
+
+	for _, margin := range []int{0, 2} {
+		side := qrCode.LargestCenteredSquare(margin)
+		if side == 0 {
+			t.Fatalf("margin %d: no square fits", margin)
+		}
+		if qrCode.DamageFromRect(centeredRect(side, side, qrCode.size)).Margin() < margin {
+			t.Errorf("margin %d: square of %d is not safe", margin, side)
+		}
+		if qrCode.DamageFromRect(centeredRect(side+1, side+1, qrCode.size)).Margin() >= margin {
+			t.Errorf("margin %d: square of %d is not the largest", margin, side)
+		}
+
+		diameter := qrCode.LargestCenteredCircle(margin)
+		if diameter < side {
+			t.Errorf("margin %d: circle (%d) smaller than the square (%d)", margin, diameter, side)
+		}
+	}
+
+	// Wipe the data under the largest square, it has to decode
+	side := qrCode.LargestCenteredSquare(0)
+	grid := qrCode.BitMatrix()
+	area := centeredRect(side, side, qrCode.size)
+	for x := area.Min.X; x < area.Max.X; x++ {
+		for y := area.Min.Y; y < area.Max.Y; y++ {
+			if !qrCode.getModule(x, y).Reserved {
+				grid[x][y] = (x*7+y*3)%5 < 2
+			}
+		}
+	}
+	if _, err := DecodeGrid(grid); err != nil {
+		t.Errorf("damage within the safe zone doesn't decode: %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+type DamageKind uint8
+
+const (
+	Damage_Flip    DamageKind = iota // Random data modules are flipped, levels are % of the data modules
+	Damage_Blank                     // Random rectangles are blanked (set to light)
+	Damage_Scratch                   // Random lines are drawn across the symbol, light or dark
+)
+
+func (kind DamageKind) String() string {
+	switch kind {
+	case Damage_Flip:
+		return "flip"
+	case Damage_Blank:
+		return "blank"
+	case Damage_Scratch:
+		return "scratch"
+	}
+	return "INVALID"
+}
+
+type StressOptions struct {
+	Kind    DamageKind
+	Levels  []float64 // Damage percentages to test. Defaults to 0, 2, 4 ... 30
+	Trials  int       // Damaged copies per level. Defaults to 20
+	Seed    int64
+	Version int // Same as EncodeOptions.Version, applied to every EC level
+
+	// Decode the rendered image instead of the module matrix. Slower, but
+	// damage to the finder patterns counts too.
+	Render bool
+}
+
+// Decode results of one EC level
+type StressResult struct {
+	EcLevel ErrorCorrectionLevel
+	Version Version
+	Points  []StressPoint
+}
+
+type StressPoint struct {
+	Damage         float64 // Requested damage, % of the symbol's modules (data modules for Damage_Flip)
+	MeasuredDamage float64 // Average damage that was actually applied
+	Trials         int
+	Decoded        int // Copies that decoded back to the exact input
+}
+
+func (p StressPoint) SuccessRate() float64 {
+	if p.Trials == 0 {
+		return 0
+	}
+	return float64(p.Decoded) / float64(p.Trials)
+}
+
+// StressTest encodes input at every EC level, damages copies of each symbol
+// and reports how many of them still decode, per damage level.
+// The same seed always produces the same damage.
+func StressTest(input string, opts StressOptions) ([]StressResult, error) {
+	levels := opts.Levels
+	if levels == nil {
+		for pct := 0.0; pct <= 30; pct += 2 {
+			levels = append(levels, pct)
+		}
+	}
+
+	trials := opts.Trials
+	if trials <= 0 {
+		trials = 20
+	}
+
+	var results []StressResult
+	for ecLevel := EC_Low; ecLevel <= EC_High; ecLevel++ {
+		qrCode, err := GenerateQRCodeWithOptions(input, EncodeOptions{EcLevel: ecLevel, Version: opts.Version})
+		if err != nil {
+			return nil, fmt.Errorf("EC %s: %w", ecLevel, err)
+		}
+
+		result := StressResult{EcLevel: ecLevel, Version: qrCode.Version}
+		rng := rand.New(rand.NewSource(opts.Seed))
+
+		for _, level := range levels {
+			point := StressPoint{Damage: level, Trials: trials}
+			for range trials {
+				damaged := qrCode.Clone()
+				point.MeasuredDamage += damaged.applyDamage(opts.Kind, level, rng) / float64(trials)
+
+				if damaged.decodesTo([]byte(input), opts.Render) {
+					point.Decoded++
+				}
+			}
+			result.Points = append(result.Points, point)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (qr *QRCode) decodesTo(expected []byte, render bool) bool {
+	if render {
+		results, err := DecodeImage(qr.GenerateImage(verifyScale))
+		return err == nil && len(results) == 1 && bytes.Equal(results[0].Data, expected)
+	}
+
+	result, err := decodeGrid(qr.BitMatrix(), nil)
+	return err == nil && bytes.Equal(result.Data, expected)
+}
+
+// Damages the symbol until at least `percent` of its modules are affected,
+// of the data modules for Damage_Flip since only those get flipped.
+// Returns the percentage that was actually affected.
+func (qr *QRCode) applyDamage(kind DamageKind, percent float64, rng *rand.Rand) float64 {
+	size := qr.size
+	total := size * size
+	positions := qr.dataPositions()
+	if kind == Damage_Flip {
+		total = len(positions)
+	}
+	target := min(int(math.Ceil(percent/100*float64(total))), total)
+
+	affected := make([][]bool, size)
+	for x := range affected {
+		affected[x] = make([]bool, size)
+	}
+	count := 0
+	mark := func(x, y int, val ModuleValue) {
+		if x < 0 || y < 0 || x >= size || y >= size {
+			return
+		}
+		qr.moduleMatrix[x][y].Value = val
+		if !affected[x][y] {
+			affected[x][y] = true
+			count++
+		}
+	}
+
+	switch kind {
+	case Damage_Flip:
+		for _, i := range rng.Perm(len(positions))[:target] {
+			x, y := positions[i][0], positions[i][1]
+			val := ValueBlack
+			if qr.moduleMatrix[x][y].Value == ValueBlack {
+				val = ValueWhite
+			}
+			mark(x, y, val)
+		}
+
+	case Damage_Blank:
+		maxSide := max(2, size/4)
+		for count < target {
+			w, h := 1+rng.Intn(maxSide), 1+rng.Intn(maxSide)
+			x0, y0 := rng.Intn(size), rng.Intn(size)
+			for x := x0; x < x0+w; x++ {
+				for y := y0; y < y0+h; y++ {
+					mark(x, y, ValueWhite)
+				}
+			}
+		}
+
+	case Damage_Scratch:
+		for count < target {
+			// From one random edge point to another, straight through the symbol
+			from, to := randomEdgePoint(size, rng), randomEdgePoint(size, rng)
+			val := ValueWhite
+			if rng.Intn(2) == 0 {
+				val = ValueBlack
+			}
+
+			steps := int(2*distance(from, to)) + 1
+			for i := 0; i <= steps; i++ {
+				t := float64(i) / float64(steps)
+				mark(int(from.X+(to.X-from.X)*t), int(from.Y+(to.Y-from.Y)*t), val)
+			}
+		}
+	}
+
+	return float64(count) * 100 / float64(total)
+}
+
+// In module coordinates
+func randomEdgePoint(size int, rng *rand.Rand) Point {
+	pos := rng.Float64() * float64(size)
+	switch rng.Intn(4) {
+	case 0:
+		return Point{pos, 0}
+	case 1:
+		return Point{pos, float64(size)}
+	case 2:
+		return Point{0, pos}
+	default:
+		return Point{float64(size), pos}
+	}
+}
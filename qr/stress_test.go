@@ -0,0 +1,51 @@
+package qr
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStressTest(t *testing.T) {
+	for _, kind := range []DamageKind{Damage_Flip, Damage_Blank, Damage_Scratch} {
+		opts := StressOptions{Kind: kind, Levels: []float64{0, 60}, Trials: 5, Seed: 42}
+
+		results, err := StressTest("https://example.com/pallet/000123", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 4 {
+			t.Fatalf("%s: expected a result per EC level, got %d", kind, len(results))
+		}
+
+		for _, r := range results {
+			if r.Points[0].SuccessRate() != 1 {
+				t.Errorf("%s EC %s: undamaged symbol should always decode", kind, r.EcLevel)
+			}
+			if r.Points[1].SuccessRate() != 0 {
+				t.Errorf("%s EC %s: 60%% damage should never decode", kind, r.EcLevel)
+			}
+			if r.Points[1].MeasuredDamage < 60 {
+				t.Errorf("%s EC %s: measured damage %.1f%% below requested", kind, r.EcLevel, r.Points[1].MeasuredDamage)
+			}
+		}
+
+		// Same seed, same damage
+		again, _ := StressTest("https://example.com/pallet/000123", opts)
+		if !reflect.DeepEqual(results, again) {
+			t.Errorf("%s: results differ for the same seed", kind)
+		}
+	}
+}
+
+// Flips only hit data modules, so the level is a share of those
+func TestStressFlipLevels(t *testing.T) {
+	results, err := StressTest("https://example.com/pallet/000123", StressOptions{Kind: Damage_Flip, Levels: []float64{100}, Trials: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if damage := r.Points[0].MeasuredDamage; damage != 100 {
+			t.Errorf("EC %s: flipped %.1f%% of the data modules, expected all of them", r.EcLevel, damage)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// Shape of the dark modules, finders excluded
+type ModuleStyle uint8
+
+const (
+	ModuleStyle_Square    ModuleStyle = iota
+	ModuleStyle_Circle                // Dots
+	ModuleStyle_Rounded               // Squares with rounded corners
+	ModuleStyle_Connected             // Neighbors merge into blobs, only free corners are rounded
+)
+
+// Shape of the three finder patterns
+type FinderStyle uint8
+
+const (
+	FinderStyle_Square  FinderStyle = iota
+	FinderStyle_Rounded             // Rounded ring and center
+	FinderStyle_Circle              // Circular eye
+)
+
+var (
+	moduleStyleNames = []string{"square", "circle", "rounded", "connected"}
+	finderStyleNames = []string{"square", "rounded", "circle"}
+)
+
+func (s ModuleStyle) String() string {
+	if int(s) < len(moduleStyleNames) {
+		return moduleStyleNames[s]
+	}
+	return "INVALID"
+}
+
+func (s FinderStyle) String() string {
+	if int(s) < len(finderStyleNames) {
+		return finderStyleNames[s]
+	}
+	return "INVALID"
+}
+
+func ParseModuleStyle(name string) (ModuleStyle, error) {
+	for i, n := range moduleStyleNames {
+		if strings.EqualFold(name, n) {
+			return ModuleStyle(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown module style %q, must be one of %s", name, strings.Join(moduleStyleNames, ", "))
+}
+
+func ParseFinderStyle(name string) (FinderStyle, error) {
+	for i, n := range finderStyleNames {
+		if strings.EqualFold(name, n) {
+			return FinderStyle(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown finder style %q, must be one of %s", name, strings.Join(finderStyleNames, ", "))
+}
+
+// In modules
+const (
+	dotRadius          = 0.45 // A little under half, so neighboring dots stay apart
+	roundedRadius      = 0.3
+	connectedRadius    = 0.5 // A lone module is a circle, a run is a pill
+	finderOuterRadius  = 2.0
+	finderInnerRadius  = 1.2
+	finderCenterRadius = 0.8
+	styleSamples       = 4 // Per pixel and axis when rasterizing, for anti-aliasing
+)
+
+type symbolStyle struct {
+	qr     *QRCode
+	module ModuleStyle
+	finder FinderStyle
+	roles  [][]ModuleInfo
+}
+
+func (qr *QRCode) style(module ModuleStyle, finder FinderStyle) *symbolStyle {
+	return &symbolStyle{qr: qr, module: module, finder: finder, roles: qr.RoleMap()}
+}
+
+// Plain squares everywhere, nothing to shape
+func squareStyle(module ModuleStyle, finder FinderStyle) bool {
+	return module == ModuleStyle_Square && finder == FinderStyle_Square
+}
+
+// Top left corners of the three finder patterns
+func (qr *QRCode) finderOrigins() []image.Point {
+	return []image.Point{{0, 0}, {qr.size - 7, 0}, {0, qr.size - 7}}
+}
+
+// Top left corner of the finder pattern that (x, y) is part of
+func (qr *QRCode) finderOrigin(x, y int) (image.Point, bool) {
+	for _, origin := range qr.finderOrigins() {
+		if image.Pt(x, y).In(image.Rectangle{origin, origin.Add(image.Pt(7, 7))}) {
+			return origin, true
+		}
+	}
+	return image.Point{}, false
+}
+
+// Corner radii of a module: top left, top right, bottom right, bottom left
+func (s *symbolStyle) radii(x, y int) [4]float64 {
+	switch s.module {
+	case ModuleStyle_Circle:
+		return [4]float64{dotRadius, dotRadius, dotRadius, dotRadius}
+	case ModuleStyle_Rounded:
+		return [4]float64{roundedRadius, roundedRadius, roundedRadius, roundedRadius}
+	case ModuleStyle_Connected:
+		// A corner is rounded when neither module next to it is dark
+		at := s.qr.At
+		free := func(dx, dy int) float64 {
+			if at(x+dx, y) || at(x, y+dy) {
+				return 0
+			}
+			return connectedRadius
+		}
+		return [4]float64{free(-1, -1), free(1, -1), free(1, 1), free(-1, 1)}
+	}
+	return [4]float64{}
+}
+
+// The shape of dark module (x, y), in symbol coordinates
+func (s *symbolStyle) moduleShape(x, y int) roundedRect {
+	if s.module == ModuleStyle_Circle {
+		return roundedRect{float64(x) + 0.5 - dotRadius, float64(y) + 0.5 - dotRadius, 2 * dotRadius, s.radii(x, y)}
+	}
+	return roundedRect{float64(x), float64(y), 1, s.radii(x, y)}
+}
+
+// A styled finder pattern is three rounded squares: the outside of the ring,
+// the hole in it and the center
+func (s *symbolStyle) finderShapes(origin image.Point) (ring, hole, center roundedRect) {
+	ox, oy := float64(origin.X), float64(origin.Y)
+	outer, inner, middle := finderOuterRadius, finderInnerRadius, finderCenterRadius
+	if s.finder == FinderStyle_Circle {
+		outer, inner, middle = 3.5, 2.5, 1.5
+	}
+	all := func(r float64) [4]float64 { return [4]float64{r, r, r, r} }
+	return roundedRect{ox, oy, 7, all(outer)}, roundedRect{ox + 1, oy + 1, 5, all(inner)}, roundedRect{ox + 2, oy + 2, 3, all(middle)}
+}
+
+// Whether the point (fx, fy) in symbol coordinates is dark
+func (s *symbolStyle) inside(fx, fy float64) bool {
+	x, y := int(math.Floor(fx)), int(math.Floor(fy))
+
+	if origin, ok := s.qr.finderOrigin(x, y); ok && s.finder != FinderStyle_Square {
+		ring, hole, center := s.finderShapes(origin)
+		return (ring.contains(fx, fy) && !hole.contains(fx, fy)) || center.contains(fx, fy)
+	}
+
+	if !s.qr.At(x, y) {
+		return false
+	}
+	if s.roles[x][y].Role == Role_Finder || s.module == ModuleStyle_Square {
+		return true
+	}
+	return s.moduleShape(x, y).contains(fx, fy)
+}
+
+type roundedRect struct {
+	x0, y0, size float64
+	radii        [4]float64 // Top left, top right, bottom right, bottom left
+}
+
+func (r roundedRect) contains(px, py float64) bool {
+	x1, y1 := r.x0+r.size, r.y0+r.size
+	if px < r.x0 || py < r.y0 || px >= x1 || py >= y1 {
+		return false
+	}
+
+	// Centers of the corner circles
+	corners := [4][2]float64{
+		{r.x0 + r.radii[0], r.y0 + r.radii[0]},
+		{x1 - r.radii[1], r.y0 + r.radii[1]},
+		{x1 - r.radii[2], y1 - r.radii[2]},
+		{r.x0 + r.radii[3], y1 - r.radii[3]},
+	}
+	for i, c := range corners {
+		rad := r.radii[i]
+		if rad == 0 {
+			continue
+		}
+		// Only the part of the corner outside the circle is cut off
+		outX := (i == 0 || i == 3) && px < c[0] || (i == 1 || i == 2) && px > c[0]
+		outY := (i == 0 || i == 1) && py < c[1] || (i == 2 || i == 3) && py > c[1]
+		if outX && outY && math.Hypot(px-c[0], py-c[1]) > rad {
+			return false
+		}
+	}
+	return true
+}
+
+// Draws the styled dark modules into img, which already has the light
+// background. Edges are anti-aliased by sampling every pixel styleSamples^2 times.
+func (s *symbolStyle) draw(img *image.RGBA, quietZone, scale int, light, dark color.RGBA) {
+	step := 1 / float64(scale*styleSamples)
+	for y := range s.qr.size {
+		for x := range s.qr.size {
+			_, finder := s.qr.finderOrigin(x, y)
+			if !s.qr.At(x, y) && !finder {
+				continue
+			}
+
+			for py := range scale {
+				for px := range scale {
+					hits := 0
+					for sy := range styleSamples {
+						for sx := range styleSamples {
+							fx := float64(x) + (float64(px*styleSamples+sx)+0.5)*step
+							fy := float64(y) + (float64(py*styleSamples+sy)+0.5)*step
+							if s.inside(fx, fy) {
+								hits++
+							}
+						}
+					}
+					if hits == 0 {
+						continue
+					}
+					img.SetRGBA((x+quietZone)*scale+px, (y+quietZone)*scale+py, blend(light, dark, float64(hits)/styleSamples/styleSamples))
+				}
+			}
+		}
+	}
+}
+
+// Premultiplied, so transparency mixes right too
+func blend(light, dark color.RGBA, coverage float64) color.RGBA {
+	mix := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a)*(1-coverage) + float64(b)*coverage))
+	}
+	return color.RGBA{mix(light.R, dark.R), mix(light.G, dark.G), mix(light.B, dark.B), mix(light.A, dark.A)}
+}
+
+// SVG subpath, clockwise for shapes and counterclockwise for holes so the
+// default nonzero fill cuts them out
+func (r roundedRect) svgPath(offset float64, clockwise bool) string {
+	x0, y0 := r.x0+offset, r.y0+offset
+	x1, y1 := x0+r.size, y0+r.size
+	tl, tr, br, bl := r.radii[0], r.radii[1], r.radii[2], r.radii[3]
+
+	var d strings.Builder
+	arc := func(rad, x, y float64, sweep int) {
+		if rad > 0 {
+			fmt.Fprintf(&d, "A%s %s 0 0 %d %s %s", formatNum(rad), formatNum(rad), sweep, formatNum(x), formatNum(y))
+		}
+	}
+	line := func(cmd string, v float64) {
+		fmt.Fprintf(&d, "%s%s", cmd, formatNum(v))
+	}
+
+	fmt.Fprintf(&d, "M%s %s", formatNum(x0+tl), formatNum(y0))
+	if clockwise {
+		line("H", x1-tr)
+		arc(tr, x1, y0+tr, 1)
+		line("V", y1-br)
+		arc(br, x1-br, y1, 1)
+		line("H", x0+bl)
+		arc(bl, x0, y1-bl, 1)
+		line("V", y0+tl)
+		arc(tl, x0+tl, y0, 1)
+	} else {
+		arc(tl, x0, y0+tl, 0)
+		line("V", y1-bl)
+		arc(bl, x0+bl, y1, 0)
+		line("H", x1-br)
+		arc(br, x1, y1-br, 0)
+		line("V", y0+tr)
+		arc(tr, x1-tr, y0, 0)
+	}
+	d.WriteString("z")
+	return d.String()
+}
+
+// Like svgPath, with every module (and finder) shaped by the style
+func (s *symbolStyle) svgPath(offset int, include func(x, y int) bool) string {
+	var d strings.Builder
+	for y := range s.qr.size {
+		for x := range s.qr.size {
+			if !s.qr.At(x, y) || !include(x, y) {
+				continue
+			}
+			if _, finder := s.qr.finderOrigin(x, y); finder && s.finder != FinderStyle_Square {
+				continue
+			}
+			if s.roles[x][y].Role == Role_Finder || s.module == ModuleStyle_Square {
+				fmt.Fprintf(&d, "M%d %dh1v1h-1z", x+offset, y+offset)
+				continue
+			}
+			d.WriteString(s.moduleShape(x, y).svgPath(float64(offset), true))
+		}
+	}
+
+	if s.finder != FinderStyle_Square {
+		for _, origin := range s.qr.finderOrigins() {
+			if !include(origin.X, origin.Y) {
+				continue
+			}
+			ring, hole, center := s.finderShapes(origin)
+			d.WriteString(ring.svgPath(float64(offset), true))
+			d.WriteString(hole.svgPath(float64(offset), false))
+			d.WriteString(center.svgPath(float64(offset), true))
+		}
+	}
+	return d.String()
+}
@@ -0,0 +1,82 @@
+package qr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStyledImagesDecode(t *testing.T) {
+	content := "https://example.com/styles"
+	qrCode := GenerateQRCode(content, EC_Medium, 0, false)
+
+	for module := ModuleStyle_Square; module <= ModuleStyle_Connected; module++ {
+		for finder := FinderStyle_Square; finder <= FinderStyle_Circle; finder++ {
+			img := qrCode.GenerateImageWithOptions(ImageOptions{Scale: 6, ModuleStyle: module, FinderStyle: finder})
+			if err := VerifyImage(img, []byte(content)); err != nil {
+				t.Errorf("%s modules, %s finders: %v", module, finder, err)
+			}
+		}
+	}
+}
+
+func TestStyleKeepsModuleCenters(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/styles", EC_Medium, 0, false)
+
+	for module := ModuleStyle_Square; module <= ModuleStyle_Connected; module++ {
+		style := qrCode.style(module, FinderStyle_Circle)
+		for y := range qrCode.Size() {
+			for x := range qrCode.Size() {
+				if _, finder := qrCode.finderOrigin(x, y); finder {
+					continue
+				}
+				if style.inside(float64(x)+0.5, float64(y)+0.5) != qrCode.At(x, y) {
+					t.Fatalf("%s: module (%d,%d) center is on the wrong side", module, x, y)
+				}
+			}
+		}
+	}
+
+	// Connected: two dark neighbors touch, no gap between them
+	style := qrCode.style(ModuleStyle_Connected, FinderStyle_Square)
+	for y := range qrCode.Size() {
+		for x := range qrCode.Size() - 1 {
+			if qrCode.At(x, y) && qrCode.At(x+1, y) && !style.inside(float64(x)+0.99, float64(y)+0.5) {
+				t.Fatalf("connected modules (%d,%d) and (%d,%d) have a gap", x, y, x+1, y)
+			}
+		}
+	}
+}
+
+func TestStyledSVG(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/styles", EC_Medium, 0, false)
+
+	var buf bytes.Buffer
+	err := qrCode.WriteSVG(&buf, SVGOptions{ModuleStyle: ModuleStyle_Circle, FinderStyle: FinderStyle_Rounded, RoleGroups: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	if strings.Contains(out, "crispEdges") {
+		t.Error("curved styles shouldn't turn off anti-aliasing")
+	}
+	// Three finders, each a ring with a hole and a center
+	finder := out[strings.Index(out, `id="qr-finder"`):]
+	finder = finder[:strings.Index(finder, "/>")]
+	if n := strings.Count(finder, "M"); n != 9 {
+		t.Errorf("finder path has %d subpaths, expected 9", n)
+	}
+	if !strings.Contains(out, "A0.45 0.45 0 0 1") {
+		t.Error("data modules should be dots")
+	}
+
+	for _, name := range []string{"square", "circle", "rounded", "connected"} {
+		if style, err := ParseModuleStyle(name); err != nil || style.String() != name {
+			t.Errorf("module style %q doesn't round trip", name)
+		}
+	}
+	if _, err := ParseFinderStyle("star"); err == nil {
+		t.Error("expected an error for an unknown finder style")
+	}
+}
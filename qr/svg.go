@@ -0,0 +1,173 @@
+package qr
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+)
+
+type SVGOptions struct {
+	Scale      int    // Pixels per module for width/height, 0 = 10
+	QuietZone  *int   // Light modules around the symbol, nil = the standard 4, QuietZone(0) = none
+	Foreground string // Any CSS color, "" = #000
+	Background string // Any CSS color, "" = #fff, "none" = transparent
+	Invert     bool   // Swaps the two colors, ignored with a Fill like ImageOptions.Invert
+
+	Responsive bool   // Only a viewBox, no width/height, so it scales to its container
+	Title      string // Accessible name (<title>)
+
+	// One path per module role, with ids like "qr-finder" or "qr-data", for
+	// styling or scripting. Otherwise all dark modules are a single path.
+	RoleGroups bool
+
+	ModuleStyle ModuleStyle
+	FinderStyle FinderStyle
+	Fill        Fill // Gradient or image for the dark modules instead of Foreground
+}
+
+const (
+	defaultSVGScale  = 10
+	defaultQuietZone = 4 // Modules, what the spec asks for
+)
+
+// QuietZone is for the QuietZone option of the renderers, which is a pointer
+// so that 0 can mean no quiet zone while leaving it out means the standard 4
+func QuietZone(modules int) *int {
+	return &modules
+}
+
+func quietZoneOf(modules *int) int {
+	if modules == nil {
+		return defaultQuietZone
+	}
+	return max(*modules, 0)
+}
+
+// WriteSVG writes the symbol as an SVG. The dark modules are horizontal runs
+// merged into one <path>, in module units (the viewBox does the scaling).
+// CSS colors are written as given, WriteCheckedSVG checks their contrast.
+func (qr *QRCode) WriteSVG(w io.Writer, opts SVGOptions) error {
+	scale := opts.Scale
+	if scale <= 0 {
+		scale = defaultSVGScale
+	}
+	quietZone := quietZoneOf(opts.QuietZone)
+	fg := orDefault(opts.Foreground, "#000")
+	bg := orDefault(opts.Background, "#fff")
+	if opts.Invert && opts.Fill == nil {
+		fg, bg = bg, fg
+	}
+
+	total := qr.size + 2*quietZone
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d"`, total, total)
+	if !opts.Responsive {
+		fmt.Fprintf(out, ` width="%d" height="%d"`, total*scale, total*scale)
+	}
+	// Curves need anti-aliasing, squares look best without
+	square := squareStyle(opts.ModuleStyle, opts.FinderStyle)
+	if square {
+		fmt.Fprint(out, ` shape-rendering="crispEdges"`)
+	}
+	fmt.Fprint(out, ">\n")
+
+	if opts.Title != "" {
+		fmt.Fprintf(out, "<title>%s</title>\n", html.EscapeString(opts.Title))
+	}
+	if bg != "none" {
+		fmt.Fprintf(out, `<rect width="%d" height="%d" fill="%s"/>`+"\n", total, total, html.EscapeString(bg))
+	}
+	if opts.Fill != nil {
+		fmt.Fprint(out, "<defs>")
+		if err := opts.Fill.writeSVGDef(out, "qr-fill", quietZone, qr.size); err != nil {
+			return err
+		}
+		fmt.Fprint(out, "</defs>\n")
+		fg = "url(#qr-fill)"
+	}
+
+	// Data modules under the logo are left out, function modules stay on top
+	covered := func(x, y int) bool { return false }
+	if qr.logo != nil {
+		if err := qr.writeSVGLogo(out, quietZone, bg); err != nil {
+			return err
+		}
+		covered = func(x, y int) bool {
+			return image.Pt(x, y).In(qr.logo.area) && !qr.getModule(x, y).Reserved
+		}
+	}
+
+	path := qr.svgPath
+	if !square {
+		path = qr.style(opts.ModuleStyle, opts.FinderStyle).svgPath
+	}
+
+	if opts.RoleGroups {
+		roles := qr.RoleMap()
+		for role := Role_Finder; role <= Role_Remainder; role++ {
+			d := path(quietZone, func(x, y int) bool {
+				return roles[x][y].Role == role && !covered(x, y)
+			})
+			if d != "" {
+				fmt.Fprintf(out, `<path id="qr-%s" fill="%s" d="%s"/>`+"\n", strings.ToLower(role.String()), html.EscapeString(fg), d)
+			}
+		}
+	} else {
+		d := path(quietZone, func(x, y int) bool { return !covered(x, y) })
+		fmt.Fprintf(out, `<path fill="%s" d="%s"/>`+"\n", html.EscapeString(fg), d)
+	}
+
+	fmt.Fprint(out, "</svg>\n")
+	return out.Flush()
+}
+
+// One subpath per horizontal run of dark modules
+func (qr *QRCode) svgPath(offset int, include func(x, y int) bool) string {
+	var d strings.Builder
+	for y := range qr.size {
+		for x := 0; x < qr.size; x++ {
+			if !qr.At(x, y) || !include(x, y) {
+				continue
+			}
+
+			start := x
+			for x+1 < qr.size && qr.At(x+1, y) && include(x+1, y) {
+				x++
+			}
+			run := x - start + 1
+			fmt.Fprintf(&d, "M%d %dh%dv1h-%dz", start+offset, y+offset, run, run)
+		}
+	}
+	return d.String()
+}
+
+// The logo goes in as an embedded PNG, on a background colored padding area
+func (qr *QRCode) writeSVGLogo(w io.Writer, quietZone int, bg string) error {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, qr.logo.img); err != nil {
+		return fmt.Errorf("failed to encode logo: %w", err)
+	}
+
+	area, inner := qr.logo.area, qr.logo.inner
+	if bg != "none" {
+		fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n",
+			area.Min.X+quietZone, area.Min.Y+quietZone, area.Dx(), area.Dy(), html.EscapeString(bg))
+	}
+	fmt.Fprintf(w, `<image x="%d" y="%d" width="%d" height="%d" preserveAspectRatio="none" href="data:image/png;base64,%s"/>`+"\n",
+		inner.Min.X+quietZone, inner.Min.Y+quietZone, inner.Dx(), inner.Dy(), base64.StdEncoding.EncodeToString(encoded.Bytes()))
+	return nil
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,157 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var svgRun = regexp.MustCompile(`M(\d+) (\d+)h(\d+)v1h-(\d+)z`)
+
+// Paints the runs of every path back into a module grid
+func svgToGrid(t *testing.T, svg string, size, quietZone int) [][]bool {
+	grid := make([][]bool, size)
+	for x := range grid {
+		grid[x] = make([]bool, size)
+	}
+
+	for _, m := range svgRun.FindAllStringSubmatch(svg, -1) {
+		x, _ := strconv.Atoi(m[1])
+		y, _ := strconv.Atoi(m[2])
+		run, _ := strconv.Atoi(m[3])
+		for i := range run {
+			if grid[x-quietZone+i][y-quietZone] {
+				t.Fatalf("module (%d,%d) painted twice", x-quietZone+i, y-quietZone)
+			}
+			grid[x-quietZone+i][y-quietZone] = true
+		}
+	}
+	return grid
+}
+
+func TestWriteSVG(t *testing.T) {
+	qrCode := GenerateQRCode("https://example.com/svg", EC_Medium, 0, false)
+
+	for _, opts := range []SVGOptions{
+		{},
+		{QuietZone: QuietZone(0), Responsive: true, Title: "Scan <me> & go", Background: "none"},
+		{QuietZone: QuietZone(2), RoleGroups: true, Foreground: "#123456"},
+	} {
+		var buf bytes.Buffer
+		if err := qrCode.WriteSVG(&buf, opts); err != nil {
+			t.Fatal(err)
+		}
+		svg := buf.String()
+
+		// Has to be well-formed XML
+		decoder := xml.NewDecoder(strings.NewReader(svg))
+		for {
+			if _, err := decoder.Token(); err != nil {
+				if err != io.EOF {
+					t.Fatalf("%+v: invalid XML: %v", opts, err)
+				}
+				break
+			}
+		}
+
+		quietZone := quietZoneOf(opts.QuietZone)
+		grid := svgToGrid(t, svg, qrCode.Size(), quietZone)
+		matrix := qrCode.BitMatrix()
+		for x := range grid {
+			for y := range grid {
+				if grid[x][y] != matrix[x][y] {
+					t.Fatalf("%+v: module (%d,%d) differs", opts, x, y)
+				}
+			}
+		}
+
+		if opts.Responsive == strings.Contains(svg, "width=\"") {
+			t.Errorf("%+v: width attribute present: %t", opts, !opts.Responsive)
+		}
+		if opts.Background == "none" && strings.Contains(svg, "<rect") {
+			t.Errorf("transparent background still has a rect")
+		}
+		if opts.Title != "" && !strings.Contains(svg, "<title>Scan &lt;me&gt; &amp; go</title>") {
+			t.Errorf("title missing or not escaped")
+		}
+		if opts.RoleGroups && (!strings.Contains(svg, `id="qr-finder"`) || !strings.Contains(svg, `id="qr-data"`)) {
+			t.Errorf("role groups missing")
+		}
+		if !opts.RoleGroups && strings.Count(svg, "<path") != 1 {
+			t.Errorf("expected a single path, got %d", strings.Count(svg, "<path"))
+		}
+	}
+}
+
+var (
+	svgRect     = regexp.MustCompile(`<rect [^>]*fill="([^"]+)"`)
+	svgPathFill = regexp.MustCompile(`<path fill="([^"]+)"`)
+	svgStop     = regexp.MustCompile(`stop-color="([^"]+)"`)
+)
+
+// Renders a plain (square module) SVG the way a browser would: the background
+// rect, then the path with its fill. A gradient counts as its first stop.
+func rasterizeSVG(t *testing.T, svg string, size, quietZone, scale int) *image.RGBA {
+	parse := func(css string) color.Color {
+		if strings.HasPrefix(css, "url(") {
+			css = svgStop.FindStringSubmatch(svg)[1]
+		}
+		c, err := cssColor(css, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	bg := parse("none")
+	if m := svgRect.FindStringSubmatch(svg); m != nil {
+		bg = parse(m[1])
+	}
+	fg := parse(svgPathFill.FindStringSubmatch(svg)[1])
+
+	grid := svgToGrid(t, svg, size, quietZone)
+	total := (size + 2*quietZone) * scale
+	img := image.NewRGBA(image.Rect(0, 0, total, total))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src) // The page
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Over)
+	for x := range grid {
+		for y := range grid {
+			if grid[x][y] {
+				r := image.Rect(x+quietZone, y+quietZone, x+quietZone+1, y+quietZone+1)
+				draw.Draw(img, image.Rectangle{r.Min.Mul(scale), r.Max.Mul(scale)}, image.NewUniform(fg), image.Point{}, draw.Over)
+			}
+		}
+	}
+	return img
+}
+
+func TestSVGInvert(t *testing.T) {
+	content := "https://example.com/svg-invert"
+	qrCode := GenerateQRCode(content, EC_Medium, 0, false)
+	fill := LinearGradient{X1: 1, Y1: 1, Stops: []GradientStop{{0, navy}, {1, purple}}}
+
+	for _, opts := range []SVGOptions{
+		{Invert: true},
+		{Invert: true, Foreground: "#1a237e", Background: "#fff8e1"},
+		{Invert: true, Fill: fill}, // Invert is ignored, like for images
+	} {
+		var buf bytes.Buffer
+		if err := qrCode.WriteSVG(&buf, opts); err != nil {
+			t.Fatal(err)
+		}
+		img := rasterizeSVG(t, buf.String(), qrCode.Size(), defaultQuietZone, 4)
+		if err := VerifyImage(img, []byte(content)); err != nil {
+			t.Errorf("%+v: %v", opts, err)
+		}
+		if opts.Fill != nil && img.RGBAAt(0, 0) != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+			t.Errorf("fill and invert: background is %v, expected white", img.RGBAAt(0, 0))
+		}
+	}
+}
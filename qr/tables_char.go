@@ -3,7 +3,7 @@ package qr
 // charCountSize[VersionGroup][EncodingMode] => bit length
 var charCountSize = [3][4]int{
 	{10, 9, 8, 8},
-	{12, 11, 16, 4},
+	{12, 11, 16, 10},
 	{14, 13, 16, 12},
 }
 
@@ -0,0 +1,71 @@
+package qr
+
+import (
+	"bufio"
+	"io"
+)
+
+type TerminalOptions struct {
+	QuietZone *int // Light modules around the symbol, nil = the standard 4, QuietZone(0) = none
+	Invert    bool // Light modules on a dark background
+
+	// Plain "##" per dark module and one line per row, no escape codes. For
+	// terminals without Unicode or colors, and for pasting into text. The
+	// light modules are whatever the terminal background is, so on a dark
+	// theme the symbol comes out inverted (see Invert).
+	ASCII bool
+}
+
+// Black on white from the 256 color palette, the 16 basic colors depend on
+// the terminal theme
+const (
+	ansiColors = "\x1b[38;5;16;48;5;231m"
+	ansiReset  = "\x1b[0m"
+)
+
+// WriteTerminal prints the symbol as text. Every line holds two module rows
+// using half-block characters, with explicit colors so it reads the same on
+// light and dark terminal themes. Logos are not drawn.
+func (qr *QRCode) WriteTerminal(w io.Writer, opts TerminalOptions) error {
+	quietZone := quietZoneOf(opts.QuietZone)
+
+	total := qr.size + 2*quietZone
+	dark := func(x, y int) bool {
+		return qr.At(x-quietZone, y-quietZone) != opts.Invert
+	}
+
+	out := bufio.NewWriter(w)
+	if opts.ASCII {
+		for y := range total {
+			for x := range total {
+				if dark(x, y) {
+					out.WriteString("##")
+				} else {
+					out.WriteString("  ")
+				}
+			}
+			out.WriteString("\n")
+		}
+		return out.Flush()
+	}
+
+	for y := 0; y < total; y += 2 {
+		out.WriteString(ansiColors)
+		for x := range total {
+			// Past the last row is light
+			top, bottom := dark(x, y), y+1 < total && dark(x, y+1)
+			switch {
+			case top && bottom:
+				out.WriteString("█")
+			case top:
+				out.WriteString("▀")
+			case bottom:
+				out.WriteString("▄")
+			default:
+				out.WriteString(" ")
+			}
+		}
+		out.WriteString(ansiReset + "\n")
+	}
+	return out.Flush()
+}
@@ -0,0 +1,54 @@
+package qr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteTerminal(t *testing.T) {
+	qrCode := GenerateQRCode("otpauth://totp/qrgen?secret=JBSWY3DPEHPK3PXP", EC_Medium, 0, false)
+	size := qrCode.Size()
+
+	var buf bytes.Buffer
+	if err := qrCode.WriteTerminal(&buf, TerminalOptions{QuietZone: QuietZone(0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Read the half blocks back into modules
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != (size+1)/2 {
+		t.Fatalf("got %d lines, expected %d", len(lines), (size+1)/2)
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, ansiColors) || !strings.HasSuffix(line, ansiReset) {
+			t.Fatalf("line %d isn't wrapped in color codes", i)
+		}
+		cells := []rune(strings.TrimSuffix(strings.TrimPrefix(line, ansiColors), ansiReset))
+		if len(cells) != size {
+			t.Fatalf("line %d has %d cells, expected %d", i, len(cells), size)
+		}
+		for x, c := range cells {
+			top := c == '█' || c == '▀'
+			bottom := c == '█' || c == '▄'
+			if top != qrCode.At(x, 2*i) || bottom != qrCode.At(x, 2*i+1) {
+				t.Fatalf("cell (%d,%d) is %q, doesn't match the matrix", x, i, c)
+			}
+		}
+	}
+
+	buf.Reset()
+	if err := qrCode.WriteTerminal(&buf, TerminalOptions{ASCII: true}); err != nil {
+		t.Fatal(err)
+	}
+	lines = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != size+8 || len(lines[0]) != 2*(size+8) {
+		t.Fatalf("ASCII output should be %d rows of %d characters", size+8, 2*(size+8))
+	}
+	if strings.ContainsAny(buf.String(), "\x1b█▀▄") {
+		t.Error("ASCII output should be plain text")
+	}
+	if lines[4][8:10] != "##" || lines[3][8:10] != "  " {
+		t.Error("finder corner should be dark, quiet zone light")
+	}
+}
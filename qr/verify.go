@@ -0,0 +1,59 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+)
+
+// Scale used to rasterize the symbol when verifying
+const verifyScale = 4
+
+// Verify reads the symbol back the way a scanner would: straight from the
+// module matrix and from the GenerateImage output. It fails unless both
+// decode to exactly `expected`, with the version, EC level and mask this
+// symbol was built with.
+func (qr *QRCode) Verify(expected []byte) error {
+	result, err := DecodeGrid(qr.BitMatrix())
+	if err != nil {
+		return fmt.Errorf("verification failed: module matrix: %w", err)
+	}
+
+	switch {
+	case result.Inverted || result.Mirrored:
+		return fmt.Errorf("verification failed: module matrix only decodes inverted/mirrored")
+	case result.Version != qr.Version || result.EcLevel != qr.EcLevel || result.Mask != qr.mask:
+		return fmt.Errorf("verification failed: module matrix decodes as version %d, EC %s, mask %d (expected version %d, EC %s, mask %d)",
+			result.Version, result.EcLevel, result.Mask, qr.Version, qr.EcLevel, qr.mask)
+	case !bytes.Equal(result.Data, expected):
+		return fmt.Errorf("verification failed: module matrix decodes to %q, expected %q", result.Data, expected)
+	}
+
+	if err := VerifyImage(qr.GenerateImage(verifyScale), expected); err != nil {
+		return err
+	}
+
+	if verbose {
+		fmt.Println("Verification: OK")
+	}
+	return nil
+}
+
+// VerifyImage fails unless img contains exactly one QR code that decodes to `expected`
+func VerifyImage(img image.Image, expected []byte) error {
+	results, err := DecodeImage(img)
+	if err != nil {
+		return fmt.Errorf("verification failed: image: %w", err)
+	}
+
+	if len(results) != 1 {
+		return fmt.Errorf("verification failed: image contains %d QR codes, expected 1", len(results))
+	}
+	if results[0].Mirrored {
+		return fmt.Errorf("verification failed: image only decodes mirrored")
+	}
+	if !bytes.Equal(results[0].Data, expected) {
+		return fmt.Errorf("verification failed: image decodes to %q, expected %q", results[0].Data, expected)
+	}
+	return nil
+}
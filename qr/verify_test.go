@@ -0,0 +1,55 @@
+package qr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	for _, input := range []string{"12345", "HELLO WORLD", "https://example.com/?q=1", "漢字テスト"} {
+		if _, err := GenerateQRCodeWithOptions(input, EncodeOptions{EcLevel: EC_Quartile, Verify: true}); err != nil {
+			t.Errorf("%q: %v", input, err)
+		}
+	}
+}
+
+func TestVerifyDetectsMismatch(t *testing.T) {
+	qrCode := GenerateQRCode("label-0001", EC_Low, 0, false)
+
+	if err := qrCode.Verify([]byte("label-0002")); err == nil {
+		t.Fatal("expected verification to fail for different content")
+	}
+
+	// Corrupt the whole data area, nothing can decode this
+	for _, pos := range qrCode.dataPositions() {
+		qrCode.setModule(pos[0], pos[1], ValueWhite, false)
+	}
+	if err := qrCode.Verify([]byte("label-0001")); err == nil {
+		t.Fatal("expected verification to fail for a corrupted symbol")
+	}
+}
+
+func TestVersionOverrideTooSmall(t *testing.T) {
+	_, err := GenerateQRCodeWithOptions(strings.Repeat("a", 100), EncodeOptions{EcLevel: EC_High, Version: 2})
+	if err == nil {
+		t.Fatal("expected an error when the data doesn't fit the requested version")
+	}
+}
+
+// Versions 10-26 use a 10 bit Kanji character count
+func TestKanjiVersionGroups(t *testing.T) {
+	input := strings.Repeat("漢字", 20)
+	for _, version := range []int{9, 10, 26, 27} {
+		qrCode, err := GenerateQRCodeWithOptions(input, EncodeOptions{EcLevel: EC_Low, Version: version})
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		result, err := DecodeGrid(qrCode.BitMatrix())
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if result.Text() != input {
+			t.Errorf("version %d: decoded %q", version, result.Text())
+		}
+	}
+}
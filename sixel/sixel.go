@@ -0,0 +1,101 @@
+// Package sixel encodes paletted images as DEC Sixel graphics, the bitmap
+// format understood by xterm, mlterm, foot, WezTerm and others. Writing the
+// output to a terminal that supports it shows the image inline.
+package sixel
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// Sixel characters hold a column of 6 pixels, bit 0 at the top
+const (
+	bandHeight  = 6
+	sixelOffset = '?'
+)
+
+// Encode writes img as a Sixel sequence. Every pixel is painted with its
+// palette color, nothing is left transparent. The color model has to be a
+// color.Palette of at most 256 colors.
+func Encode(w io.Writer, img image.PalettedImage) error {
+	palette, ok := img.ColorModel().(color.Palette)
+	if !ok {
+		return fmt.Errorf("image color model is not a palette")
+	}
+	if len(palette) == 0 || len(palette) > 256 {
+		return fmt.Errorf("palette has %d colors, must be 1-256", len(palette))
+	}
+
+	bounds := img.Bounds()
+	out := bufio.NewWriter(w)
+
+	// DCS with P2 = 1 (unpainted pixels stay as they are), then the raster
+	// attributes: 1:1 pixel aspect ratio and the image size
+	fmt.Fprintf(out, "\x1bP0;1;0q\"1;1;%d;%d", bounds.Dx(), bounds.Dy())
+
+	// Color registers in RGB percent
+	for i, c := range palette {
+		r, g, b, _ := c.RGBA()
+		fmt.Fprintf(out, "#%d;2;%d;%d;%d", i, percent(r), percent(g), percent(b))
+	}
+
+	row := make([]byte, bounds.Dx())
+	for y0 := bounds.Min.Y; y0 < bounds.Max.Y; y0 += bandHeight {
+		// One pass over the band per color, `$` goes back to its start
+		first := true
+		for index := range palette {
+			used := false
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				bits := byte(0)
+				for dy := range min(bandHeight, bounds.Max.Y-y0) {
+					if int(img.ColorIndexAt(x, y0+dy)) == index {
+						bits |= 1 << dy
+					}
+				}
+				row[x-bounds.Min.X] = sixelOffset + bits
+				used = used || bits != 0
+			}
+			if !used {
+				continue
+			}
+
+			if !first {
+				out.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(out, "#%d", index)
+			// Nothing to paint after the last set pixel
+			writeRuns(out, bytes.TrimRight(row, string(rune(sixelOffset))))
+		}
+		out.WriteByte('-')
+	}
+
+	out.WriteString("\x1b\\")
+	return out.Flush()
+}
+
+// Repeats of the same sixel are run length encoded as !<count><sixel>
+func writeRuns(out *bufio.Writer, row []byte) {
+	for i := 0; i < len(row); {
+		j := i
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		if run := j - i; run > 3 {
+			fmt.Fprintf(out, "!%d%c", run, row[i])
+		} else {
+			for range run {
+				out.WriteByte(row[i])
+			}
+		}
+		i = j
+	}
+}
+
+func percent(v uint32) int {
+	return int((v*100 + 0x7fff) / 0xffff)
+}
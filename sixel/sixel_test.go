@@ -0,0 +1,114 @@
+package sixel
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	palette := color.Palette{color.White, color.Black, color.RGBA{255, 0, 0, 255}}
+	img := image.NewPaletted(image.Rect(0, 0, 23, 13), palette)
+	for y := range 13 {
+		for x := range 23 {
+			img.SetColorIndex(x, y, uint8((x/3+y/2)%3))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	if !strings.HasPrefix(out, "\x1bP0;1;0q\"1;1;23;13") || !strings.HasSuffix(out, "\x1b\\") {
+		t.Fatalf("bad framing: %q", out)
+	}
+	if !strings.Contains(out, "#0;2;100;100;100#1;2;0;0;0#2;2;100;0;0") {
+		t.Error("missing color registers")
+	}
+
+	got := decode(t, out, 23, 13)
+	for y := range 13 {
+		for x := range 23 {
+			if got[y][x] != int(img.ColorIndexAt(x, y)) {
+				t.Fatalf("pixel (%d,%d) is color %d, expected %d", x, y, got[y][x], img.ColorIndexAt(x, y))
+			}
+		}
+	}
+}
+
+func TestEncodeRejectsNonPaletted(t *testing.T) {
+	img := &image.Paletted{Rect: image.Rect(0, 0, 1, 1), Pix: []uint8{0}, Stride: 1}
+	if err := Encode(&bytes.Buffer{}, img); err == nil {
+		t.Error("expected an error for an empty palette")
+	}
+}
+
+// Minimal decoder for what Encode writes
+func decode(t *testing.T, s string, width, height int) [][]int {
+	pixels := make([][]int, height)
+	for y := range pixels {
+		pixels[y] = make([]int, width)
+		for x := range pixels[y] {
+			pixels[y][x] = -1
+		}
+	}
+
+	// After the raster attributes, up to the string terminator
+	body := s[strings.Index(s, "#") : len(s)-2]
+
+	x, y0, c := 0, 0, 0
+	number := func(i int) (int, int) {
+		j := i
+		for j < len(body) && body[j] >= '0' && body[j] <= '9' {
+			j++
+		}
+		n, err := strconv.Atoi(body[i:j])
+		if err != nil {
+			t.Fatalf("bad number at %d", i)
+		}
+		return n, j
+	}
+	paint := func(ch byte, count int) {
+		for range count {
+			for dy := range 6 {
+				if (ch-'?')&(1<<dy) != 0 && y0+dy < height {
+					pixels[y0+dy][x] = c
+				}
+			}
+			x++
+		}
+	}
+
+	for i := 0; i < len(body); {
+		switch ch := body[i]; {
+		case ch == '#':
+			c, i = number(i + 1)
+			// Color register definition: #c;2;r;g;b
+			for range 4 {
+				if i < len(body) && body[i] == ';' {
+					_, i = number(i + 1)
+				}
+			}
+		case ch == '!':
+			var n int
+			n, i = number(i + 1)
+			paint(body[i], n)
+			i++
+		case ch == '$':
+			x = 0
+			i++
+		case ch == '-':
+			x, y0 = 0, y0+6
+			i++
+		default:
+			paint(ch, 1)
+			i++
+		}
+	}
+	return pixels
+}